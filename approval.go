@@ -0,0 +1,245 @@
+package sdk
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 审批表单中常用的控件类型(component_type)
+const (
+	TextField          = "TextField"          // 单行输入框
+	TextareaField      = "TextareaField"      // 多行输入框
+	NumberField        = "NumberField"        // 数字输入框
+	MoneyField         = "MoneyField"         // 金额
+	CalculateField     = "CalculateField"     // 计算公式
+	DDSelectField      = "DDSelectField"      // 单选框
+	DDMultiSelectField = "DDMultiSelectField" // 多选框
+	DDDateField        = "DDDateField"        // 日期
+	DDDateRangeField   = "DDDateRangeField"   // 日期区间
+	DDPhotoField       = "DDPhotoField"       // 图片
+	DDAttachment       = "DDAttachment"       // 附件
+	TableField         = "TableField"         // 明细
+	InnerContactField  = "InnerContactField"  // 联系人
+	DepartmentField    = "DepartmentField"    // 部门
+	TextNote           = "TextNote"           // 说明文字
+)
+
+// 审批实例的状态(status)
+const (
+	ApprovalStatusNew        = "NEW"        // 新创建
+	ApprovalStatusRunning    = "RUNNING"    // 审批中
+	ApprovalStatusTerminated = "TERMINATED" // 被终止
+	ApprovalStatusCompleted  = "COMPLETED"  // 完成
+	ApprovalStatusCanceled   = "CANCELED"   // 取消
+)
+
+var approvalDateLayouts = []string{
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+type approvalTableRow struct {
+	RowValue []struct {
+		Label string          `json:"label"`
+		Value json.RawMessage `json:"value"`
+	} `json:"rowValue"`
+}
+
+// AsTable 将明细控件(TableField)的值解析为每一行 label -> value 的映射
+func (c *ApprovalComponent) AsTable() ([]map[string]string, error) {
+	if c.Type != TableField {
+		return nil, fmt.Errorf("控件(%s)类型为%s, 不是明细控件", c.Name, c.Type)
+	}
+
+	if isEmptyComponentValue(c.Value) {
+		return nil, nil
+	}
+
+	var rows []approvalTableRow
+	if err := json.Unmarshal([]byte(c.Value), &rows); err != nil {
+		return nil, fmt.Errorf("解析明细控件(%s)失败: %w", c.Name, err)
+	}
+
+	data := make([]map[string]string, 0, len(rows))
+	for _, row := range rows {
+		item := make(map[string]string, len(row.RowValue))
+		for _, col := range row.RowValue {
+			var val string
+			// 明细中的值可能是字符串，也可能是数字、数组等JSON值
+			if err := json.Unmarshal(col.Value, &val); err != nil {
+				val = string(col.Value)
+			}
+			item[col.Label] = val
+		}
+		data = append(data, item)
+	}
+	return data, nil
+}
+
+// AsDateRange 将日期区间控件(DDDateRangeField)的值解析为开始和结束时间
+// 钉钉返回的值形如: ["2021-09-13 09:00","2021-09-14 18:00",1.5,"day"]
+func (c *ApprovalComponent) AsDateRange() (start, end time.Time, err error) {
+	if c.Type != DDDateRangeField {
+		return start, end, fmt.Errorf("控件(%s)类型为%s, 不是日期区间控件", c.Name, c.Type)
+	}
+
+	var values []interface{}
+	if err = json.Unmarshal([]byte(c.Value), &values); err != nil {
+		return start, end, fmt.Errorf("解析日期区间控件(%s)失败: %w", c.Name, err)
+	}
+
+	if len(values) < 2 {
+		return start, end, fmt.Errorf("日期区间控件(%s)的值无效: %s", c.Name, c.Value)
+	}
+
+	startVal, ok1 := values[0].(string)
+	endVal, ok2 := values[1].(string)
+	if !ok1 || !ok2 {
+		return start, end, fmt.Errorf("日期区间控件(%s)的值无效: %s", c.Name, c.Value)
+	}
+
+	if start, err = parseApprovalDate(startVal); err != nil {
+		return start, end, err
+	}
+
+	if end, err = parseApprovalDate(endVal); err != nil {
+		return start, end, err
+	}
+
+	return start, end, nil
+}
+
+// AsDate 将日期控件(DDDateField)的值解析为时间
+func (c *ApprovalComponent) AsDate() (time.Time, error) {
+	if c.Type != DDDateField {
+		return time.Time{}, fmt.Errorf("控件(%s)类型为%s, 不是日期控件", c.Name, c.Type)
+	}
+
+	return parseApprovalDate(c.Value)
+}
+
+// AsNumber 将数字、金额、计算公式控件的值解析为浮点数
+func (c *ApprovalComponent) AsNumber() (float64, error) {
+	switch c.Type {
+	case NumberField, MoneyField, CalculateField:
+	default:
+		return 0, fmt.Errorf("控件(%s)类型为%s, 不是数值控件", c.Name, c.Type)
+	}
+
+	if isEmptyComponentValue(c.Value) {
+		return 0, nil
+	}
+
+	num, err := strconv.ParseFloat(strings.TrimSpace(c.Value), 64)
+	if err != nil {
+		return 0, fmt.Errorf("解析数值控件(%s)失败: %w", c.Name, err)
+	}
+	return num, nil
+}
+
+// approvalAttachmentValue 附件控件中的spaceId和fileSize可能是数字也可能是字符串，统一使用json.Number解析
+type approvalAttachmentValue struct {
+	SpaceID  json.Number `json:"spaceId"`
+	FileID   string      `json:"fileId"`
+	FileName string      `json:"fileName"`
+	FileSize json.Number `json:"fileSize"`
+	FileType string      `json:"fileType"`
+}
+
+// AsAttachments 将附件控件(DDAttachment)的值解析为附件列表
+func (c *ApprovalComponent) AsAttachments() ([]*ApprovalAttachment, error) {
+	if c.Type != DDAttachment {
+		return nil, fmt.Errorf("控件(%s)类型为%s, 不是附件控件", c.Name, c.Type)
+	}
+
+	if isEmptyComponentValue(c.Value) {
+		return nil, nil
+	}
+
+	var values []approvalAttachmentValue
+	if err := json.Unmarshal([]byte(c.Value), &values); err != nil {
+		return nil, fmt.Errorf("解析附件控件(%s)失败: %w", c.Name, err)
+	}
+
+	data := make([]*ApprovalAttachment, 0, len(values))
+	for _, v := range values {
+		size, _ := v.FileSize.Int64()
+		data = append(data, &ApprovalAttachment{
+			SpaceID:  v.SpaceID.String(),
+			FileID:   v.FileID,
+			FileName: v.FileName,
+			FileSize: size,
+			FileType: v.FileType,
+		})
+	}
+	return data, nil
+}
+
+// IsFinished 审批实例是否已经结束(完成、被终止或被撤销)
+// 除新创建和审批中以外的状态都视为已结束，避免钉钉新增的终态导致WaitApprovalDetail一直轮询
+func (a *ApprovalDetail) IsFinished() bool {
+	switch a.Status {
+	case "", ApprovalStatusNew, ApprovalStatusRunning:
+		return false
+	}
+	return true
+}
+
+// FormValues 返回审批表单中控件名称到控件值的映射
+// 多个控件名称相同时只保留最后一个，需要全部的值时使用FormValuesMulti
+func (a *ApprovalDetail) FormValues() map[string]string {
+	values := make(map[string]string, len(a.Components))
+	for _, c := range a.Components {
+		values[c.Name] = c.Value
+	}
+	return values
+}
+
+// FormValuesMulti 返回审批表单中控件名称到控件值列表的映射，同名控件的值按表单中的顺序保存
+func (a *ApprovalDetail) FormValuesMulti() map[string][]string {
+	values := make(map[string][]string, len(a.Components))
+	for _, c := range a.Components {
+		values[c.Name] = append(values[c.Name], c.Value)
+	}
+	return values
+}
+
+// Attachments 汇总审批表单附件控件及操作记录中的全部附件
+func (a *ApprovalDetail) Attachments() ([]*ApprovalAttachment, error) {
+	var data []*ApprovalAttachment
+	for _, c := range a.Components {
+		if c.Type != DDAttachment {
+			continue
+		}
+
+		items, err := c.AsAttachments()
+		if err != nil {
+			return nil, err
+		}
+		data = append(data, items...)
+	}
+
+	for _, record := range a.OperationRecords {
+		data = append(data, record.Attachments...)
+	}
+	return data, nil
+}
+
+func parseApprovalDate(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	for _, layout := range approvalDateLayouts {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("无法识别的日期格式: %s", value)
+}
+
+func isEmptyComponentValue(value string) bool {
+	value = strings.TrimSpace(value)
+	return value == "" || value == "null"
+}
@@ -0,0 +1,22 @@
+package sdk
+
+import "testing"
+
+func TestApprovalDetailIsFinished(t *testing.T) {
+	cases := map[string]bool{
+		"":                       false,
+		ApprovalStatusNew:        false,
+		ApprovalStatusRunning:    false,
+		ApprovalStatusCompleted:  true,
+		ApprovalStatusTerminated: true,
+		ApprovalStatusCanceled:   true,
+		"UNKNOWN":                true,
+	}
+
+	for status, want := range cases {
+		detail := &ApprovalDetail{Status: status}
+		if got := detail.IsFinished(); got != want {
+			t.Errorf("IsFinished(%q) = %v, want %v", status, got, want)
+		}
+	}
+}
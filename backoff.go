@@ -1,6 +1,7 @@
 package sdk
 
 import (
+	"fmt"
 	"math/rand"
 	"time"
 )
@@ -12,11 +13,14 @@ var (
 	defaultJitter    = 0.2
 )
 
+// Backoff 指数退避策略，第n次重试的等待时间为baseDelay*factor^n，不超过MaxDelay，并加上±jitter比例的随机抖动
+// MaxDelay可以直接读取和修改，其它参数创建后不可修改，可以通过BaseDelay、Factor、Jitter读取
 type Backoff struct {
 	MaxDelay  time.Duration
 	baseDelay time.Duration
 	factor    float64
 	jitter    float64
+	random    func() float64 // 计算抖动使用的随机数，默认使用全局的rand.Float64
 }
 
 func NewBackoff() *Backoff {
@@ -37,9 +41,64 @@ func NewBackoff() *Backoff {
 		baseDelay: baseDelay,
 		factor:    factor,
 		jitter:    jitter,
+		random:    rand.Float64,
 	}
 }
 
+// NewBackoffWithParams 使用自定义参数创建Backoff
+// 要求baseDelay > 0、baseDelay <= maxDelay、factor > 1，jitter取值范围为[0, 1]
+func NewBackoffWithParams(baseDelay, maxDelay time.Duration, factor, jitter float64) (*Backoff, error) {
+	if baseDelay <= 0 {
+		return nil, fmt.Errorf("baseDelay(%v)必须大于0", baseDelay)
+	}
+
+	if baseDelay > maxDelay {
+		return nil, fmt.Errorf("baseDelay(%v)不能大于maxDelay(%v)", baseDelay, maxDelay)
+	}
+
+	if factor <= 1 {
+		return nil, fmt.Errorf("factor(%v)必须大于1", factor)
+	}
+
+	if jitter < 0 || jitter > 1 {
+		return nil, fmt.Errorf("jitter(%v)的取值范围为[0, 1]", jitter)
+	}
+
+	return &Backoff{
+		MaxDelay:  maxDelay,
+		baseDelay: baseDelay,
+		factor:    factor,
+		jitter:    jitter,
+		random:    rand.Float64,
+	}, nil
+}
+
+// WithRand 返回使用指定随机数源计算抖动的副本，不会修改bc，传入固定种子的随机数源可以得到确定的退避时间，便于测试
+// 注意*rand.Rand不是并发安全的，共享返回的Backoff时需要自行保证并发安全
+func (bc *Backoff) WithRand(r *rand.Rand) *Backoff {
+	cp := *bc
+	if r != nil {
+		cp.random = r.Float64
+	}
+	return &cp
+}
+
+// BaseDelay 第一次重试的等待时间
+func (bc *Backoff) BaseDelay() time.Duration {
+	return bc.baseDelay
+}
+
+// Factor 每次重试等待时间的增长倍数
+func (bc *Backoff) Factor() float64 {
+	return bc.factor
+}
+
+// Jitter 随机抖动的比例
+func (bc *Backoff) Jitter() float64 {
+	return bc.jitter
+}
+
+// Duration 计算第retries次重试前的等待时间，不会修改Backoff的状态
 func (bc *Backoff) Duration(retries int) time.Duration {
 	if retries <= 0 {
 		return bc.baseDelay
@@ -55,7 +114,12 @@ func (bc *Backoff) Duration(retries int) time.Duration {
 		backoff = max
 	}
 
-	backoff *= 1 + bc.jitter*(rand.Float64()*2-1)
+	random := bc.random
+	if random == nil {
+		random = rand.Float64
+	}
+
+	backoff *= 1 + bc.jitter*(random()*2-1)
 	if backoff < 0 {
 		return 0
 	}
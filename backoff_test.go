@@ -0,0 +1,88 @@
+package sdk
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestBackoffDuration(t *testing.T) {
+	backOff, err := NewBackoffWithParams(time.Second, 10*time.Second, 2, 0)
+	if err != nil {
+		t.Fatalf("NewBackoffWithParams: %v", err)
+	}
+
+	cases := map[int]time.Duration{
+		0:   time.Second,
+		1:   2 * time.Second,
+		3:   8 * time.Second,
+		4:   10 * time.Second,
+		100: 10 * time.Second,
+	}
+	for retries, want := range cases {
+		if got := backOff.Duration(retries); got != want {
+			t.Errorf("Duration(%d) = %v, want %v", retries, got, want)
+		}
+	}
+}
+
+func TestBackoffJitterBounds(t *testing.T) {
+	backOff := NewBackoff().WithRand(rand.New(rand.NewSource(1)))
+
+	for retries := 1; retries <= 20; retries++ {
+		base := float64(defaultBaseDelay)
+		for i := 0; i < retries && base < float64(defaultMaxDelay); i++ {
+			base *= defaultFactor
+		}
+		if base > float64(defaultMaxDelay) {
+			base = float64(defaultMaxDelay)
+		}
+
+		got := float64(backOff.Duration(retries))
+		if got < base*(1-defaultJitter) || got > base*(1+defaultJitter) {
+			t.Errorf("Duration(%d) = %v, want within ±%v of %v", retries, time.Duration(got), defaultJitter, time.Duration(base))
+		}
+	}
+}
+
+func TestBackoffWithRandCopy(t *testing.T) {
+	orig := NewBackoff()
+	a := orig.WithRand(rand.New(rand.NewSource(42)))
+	b := orig.WithRand(rand.New(rand.NewSource(42)))
+
+	if a == orig {
+		t.Fatal("WithRand应返回副本")
+	}
+	for retries := 1; retries <= 5; retries++ {
+		if a.Duration(retries) != b.Duration(retries) {
+			t.Fatalf("相同种子的Duration(%d)不一致", retries)
+		}
+	}
+}
+
+func TestNewBackoffWithParamsValidate(t *testing.T) {
+	cases := []struct {
+		base, max      time.Duration
+		factor, jitter float64
+		ok             bool
+	}{
+		{time.Second, time.Minute, 1.6, 0.2, true},
+		{time.Second, time.Second, 2, 0, true},
+		{0, time.Minute, 1.6, 0.2, false},
+		{time.Minute, time.Second, 1.6, 0.2, false},
+		{time.Second, time.Minute, 1, 0.2, false},
+		{time.Second, time.Minute, 1.6, -0.1, false},
+		{time.Second, time.Minute, 1.6, 1.1, false},
+	}
+
+	for _, c := range cases {
+		backOff, err := NewBackoffWithParams(c.base, c.max, c.factor, c.jitter)
+		if (err == nil) != c.ok {
+			t.Errorf("NewBackoffWithParams(%v, %v, %v, %v) err = %v, want ok=%v", c.base, c.max, c.factor, c.jitter, err, c.ok)
+			continue
+		}
+		if c.ok && (backOff.BaseDelay() != c.base || backOff.MaxDelay != c.max || backOff.Factor() != c.factor || backOff.Jitter() != c.jitter) {
+			t.Errorf("参数未生效: %+v", backOff)
+		}
+	}
+}
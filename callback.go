@@ -0,0 +1,189 @@
+package sdk
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// CallbackSignature 计算事件订阅回调的签名: 将token、timestamp、nonce、encrypt按字典序排序后拼接，再做SHA1
+func CallbackSignature(token, timestamp, nonce, encrypt string) string {
+	items := []string{token, timestamp, nonce, encrypt}
+	sort.Strings(items)
+	digest := sha1.Sum([]byte(strings.Join(items, "")))
+	return hex.EncodeToString(digest[:])
+}
+
+// VerifyCallbackSignature 校验钉钉推送事件的签名(msg_signature)是否合法
+func VerifyCallbackSignature(token, timestamp, nonce, signature, encrypt string) bool {
+	expected := CallbackSignature(token, timestamp, nonce, encrypt)
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(signature)) == 1
+}
+
+// DecryptCallback 解密钉钉推送事件中的encrypt字段
+// aesKey为开发者后台配置的43位加密aes_key，返回事件内容明文以及消息接收方(企业内部应用为AppKey，第三方应用为SuiteKey)
+func DecryptCallback(aesKey, encrypt string) (msg string, receiveID string, err error) {
+	key, err := decodeAESKey(aesKey)
+	if err != nil {
+		return "", "", err
+	}
+	return decryptCallback(key, encrypt)
+}
+
+func decryptCallback(key []byte, encrypt string) (msg string, receiveID string, err error) {
+	data, err := base64.StdEncoding.DecodeString(encrypt)
+	if err != nil {
+		return "", "", fmt.Errorf("解析回调密文失败: %w", err)
+	}
+
+	if len(data) == 0 || len(data)%aes.BlockSize != 0 {
+		return "", "", fmt.Errorf("回调密文长度(%d)无效", len(data))
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return "", "", fmt.Errorf("初始化解密失败: %w", err)
+	}
+
+	plain := make([]byte, len(data))
+	cipher.NewCBCDecrypter(block, key[:aes.BlockSize]).CryptBlocks(plain, data)
+
+	if plain, err = pkcs7Unpad(plain); err != nil {
+		return "", "", err
+	}
+
+	// 明文格式: 16字节随机串 + 4字节消息长度(网络字节序) + 消息内容 + receiveID
+	if len(plain) < 20 {
+		return "", "", fmt.Errorf("回调明文长度(%d)无效", len(plain))
+	}
+
+	size := int(binary.BigEndian.Uint32(plain[16:20]))
+	if size > len(plain)-20 {
+		return "", "", fmt.Errorf("回调消息长度(%d)无效", size)
+	}
+
+	return string(plain[20 : 20+size]), string(plain[20+size:]), nil
+}
+
+func encryptCallback(key []byte, msg, receiveID string) (string, error) {
+	random := make([]byte, 16)
+	if _, err := rand.Read(random); err != nil {
+		return "", fmt.Errorf("生成随机串失败: %w", err)
+	}
+
+	size := make([]byte, 4)
+	binary.BigEndian.PutUint32(size, uint32(len(msg)))
+
+	plain := make([]byte, 0, 20+len(msg)+len(receiveID)+32)
+	plain = append(plain, random...)
+	plain = append(plain, size...)
+	plain = append(plain, msg...)
+	plain = append(plain, receiveID...)
+	plain = pkcs7Pad(plain)
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return "", fmt.Errorf("初始化加密失败: %w", err)
+	}
+
+	data := make([]byte, len(plain))
+	cipher.NewCBCEncrypter(block, key[:aes.BlockSize]).CryptBlocks(data, plain)
+	return base64.StdEncoding.EncodeToString(data), nil
+}
+
+func decodeAESKey(aesKey string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(aesKey + "=")
+	if err != nil {
+		return nil, fmt.Errorf("解析aes_key失败: %w", err)
+	}
+
+	if len(key) != 32 {
+		return nil, fmt.Errorf("aes_key长度无效, 应为43位")
+	}
+	return key, nil
+}
+
+// pkcs7Pad 钉钉回调加密使用32字节为块大小的PKCS#7填充
+func pkcs7Pad(data []byte) []byte {
+	padding := 32 - len(data)%32
+	return append(data, bytes.Repeat([]byte{byte(padding)}, padding)...)
+}
+
+func pkcs7Unpad(data []byte) ([]byte, error) {
+	padding := int(data[len(data)-1])
+	if padding < 1 || padding > 32 || padding > len(data) {
+		return nil, fmt.Errorf("回调明文填充(%d)无效", padding)
+	}
+	return data[:len(data)-padding], nil
+}
+
+// Crypto 事件订阅回调的加解密工具
+//
+//	crypto, err := sdk.NewCrypto(token, aesKey, appKey)
+//	event, err := crypto.Decrypt(signature, timestamp, nonce, body.Encrypt)
+//	...
+//	resp, err := crypto.Encrypt("success", timestamp, nonce)
+type Crypto struct {
+	token  string
+	key    []byte
+	corpId string // 消息接收方，企业内部应用为AppKey，第三方应用为SuiteKey
+}
+
+// CallbackResponse 回调接口需要返回给钉钉的加密数据
+type CallbackResponse struct {
+	MsgSignature string `json:"msg_signature"`
+	TimeStamp    string `json:"timeStamp"`
+	Nonce        string `json:"nonce"`
+	Encrypt      string `json:"encrypt"`
+}
+
+// NewCrypto 使用开发者后台配置的签名token、加密aes_key以及corpId(企业内部应用为AppKey)创建加解密工具
+func NewCrypto(token, aesKey, corpId string) (*Crypto, error) {
+	key, err := decodeAESKey(aesKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Crypto{token: token, key: key, corpId: corpId}, nil
+}
+
+// Decrypt 校验签名并解密回调中的encrypt字段，返回事件内容明文
+func (c *Crypto) Decrypt(signature, timestamp, nonce, encrypt string) (string, error) {
+	if !VerifyCallbackSignature(c.token, timestamp, nonce, signature, encrypt) {
+		return "", fmt.Errorf("回调签名校验失败")
+	}
+
+	msg, receiveID, err := decryptCallback(c.key, encrypt)
+	if err != nil {
+		return "", err
+	}
+
+	if receiveID != c.corpId {
+		return "", fmt.Errorf("回调消息接收方(%s)与corpId不一致", receiveID)
+	}
+	return msg, nil
+}
+
+// Encrypt 加密回调的响应内容(通常为"success")并签名
+func (c *Crypto) Encrypt(msg, timestamp, nonce string) (*CallbackResponse, error) {
+	encrypt, err := encryptCallback(c.key, msg, c.corpId)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CallbackResponse{
+		MsgSignature: CallbackSignature(c.token, timestamp, nonce, encrypt),
+		TimeStamp:    timestamp,
+		Nonce:        nonce,
+		Encrypt:      encrypt,
+	}, nil
+}
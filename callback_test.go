@@ -0,0 +1,89 @@
+package sdk
+
+import (
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+// testAESKey 43位的aes_key，解码后为32字节
+var testAESKey = strings.TrimSuffix(base64.StdEncoding.EncodeToString([]byte("0123456789abcdef0123456789abcdef")), "=")
+
+func TestCallbackSignature(t *testing.T) {
+	// 按字典序排序后拼接: timestamp < encrypt < nonce < token
+	digest := sha1.Sum([]byte("1700000000encryptnoncetoken"))
+	want := hex.EncodeToString(digest[:])
+
+	if got := CallbackSignature("token", "1700000000", "nonce", "encrypt"); got != want {
+		t.Fatalf("CallbackSignature = %s, want %s", got, want)
+	}
+
+	if !VerifyCallbackSignature("token", "1700000000", "nonce", want, "encrypt") {
+		t.Error("合法的签名校验失败")
+	}
+	if VerifyCallbackSignature("token", "1700000001", "nonce", want, "encrypt") {
+		t.Error("timestamp不一致时签名校验应失败")
+	}
+}
+
+func TestCryptoRoundTrip(t *testing.T) {
+	crypto, err := NewCrypto("token", testAESKey, "app-key")
+	if err != nil {
+		t.Fatalf("NewCrypto: %v", err)
+	}
+
+	// 长度覆盖刚好填满一个块以及需要填充的情况
+	for _, msg := range []string{"success", `{"EventType":"check_url"}`, strings.Repeat("x", 32-20-len("app-key"))} {
+		resp, err := crypto.Encrypt(msg, "1700000000", "nonce")
+		if err != nil {
+			t.Fatalf("Encrypt: %v", err)
+		}
+
+		if !VerifyCallbackSignature("token", resp.TimeStamp, resp.Nonce, resp.MsgSignature, resp.Encrypt) {
+			t.Errorf("Encrypt返回的签名无效")
+		}
+
+		got, err := crypto.Decrypt(resp.MsgSignature, resp.TimeStamp, resp.Nonce, resp.Encrypt)
+		if err != nil || got != msg {
+			t.Errorf("Decrypt = %q, %v, want %q", got, err, msg)
+		}
+
+		plain, receiveID, err := DecryptCallback(testAESKey, resp.Encrypt)
+		if err != nil || plain != msg || receiveID != "app-key" {
+			t.Errorf("DecryptCallback = %q, %q, %v", plain, receiveID, err)
+		}
+	}
+}
+
+func TestCryptoDecryptRejects(t *testing.T) {
+	crypto, _ := NewCrypto("token", testAESKey, "app-key")
+	other, _ := NewCrypto("token", testAESKey, "other-key")
+
+	resp, err := other.Encrypt("success", "1700000000", "nonce")
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+
+	if _, err = crypto.Decrypt("bad-signature", resp.TimeStamp, resp.Nonce, resp.Encrypt); err == nil {
+		t.Error("签名错误时应返回错误")
+	}
+	if _, err = crypto.Decrypt(resp.MsgSignature, resp.TimeStamp, resp.Nonce, resp.Encrypt); err == nil {
+		t.Error("接收方与corpId不一致时应返回错误")
+	}
+
+	for _, encrypt := range []string{"", "not base64!", base64.StdEncoding.EncodeToString([]byte("short"))} {
+		if _, _, err = DecryptCallback(testAESKey, encrypt); err == nil {
+			t.Errorf("DecryptCallback(%q) 应返回错误", encrypt)
+		}
+	}
+}
+
+func TestNewCryptoInvalidKey(t *testing.T) {
+	for _, key := range []string{"", "short", testAESKey + "A"} {
+		if _, err := NewCrypto("token", key, "app-key"); err == nil {
+			t.Errorf("NewCrypto(%q) 应返回错误", key)
+		}
+	}
+}
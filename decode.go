@@ -0,0 +1,131 @@
+package sdk
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// maxDecodeFixes 解析响应时最多修正的字段类型次数
+const maxDecodeFixes = 8
+
+// fixNumericField 修正响应中数字与字符串类型不一致的字段
+// 钉钉不同接口对同一字段的类型并不统一，如errcode、dept_id有时返回"0"这样的字符串，有时字符串字段又返回数字。
+// 解析出现类型错误时，只转换错误路径(UnmarshalTypeError.Field)上的字段并返回新的响应内容，无法修正时返回false。
+// 路径中的数组下标匹配数组的全部元素：同一数组的元素对应相同的类型，一次修正整个数组，避免逐个元素重新解析
+func fixNumericField(payload []byte, err error) ([]byte, bool) {
+	var typeErr *json.UnmarshalTypeError
+	if !errors.As(err, &typeErr) || typeErr.Type == nil || typeErr.Field == "" {
+		return nil, false
+	}
+
+	var toNumber bool
+	switch typeErr.Type.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		if typeErr.Value != "string" {
+			return nil, false
+		}
+		toNumber = true
+	case reflect.String:
+		if typeErr.Value != "number" {
+			return nil, false
+		}
+	default:
+		return nil, false
+	}
+
+	var data interface{}
+	decoder := json.NewDecoder(bytes.NewReader(payload))
+	decoder.UseNumber()
+	if decoder.Decode(&data) != nil {
+		return nil, false
+	}
+
+	data, changed := convertPath(data, strings.Split(typeErr.Field, "."), toNumber)
+	if !changed {
+		return nil, false
+	}
+
+	fixed, err := json.Marshal(data)
+	if err != nil {
+		return nil, false
+	}
+	return fixed, true
+}
+
+// convertPath 将path指向的值转换为数字(toNumber为true)或字符串，返回转换后的值以及是否有值被转换
+// 遇到数组时，path中对应的下标(旧版本Go的错误路径中没有下标)匹配数组的全部元素
+func convertPath(data interface{}, path []string, toNumber bool) (interface{}, bool) {
+	switch val := data.(type) {
+	case map[string]interface{}:
+		if len(path) == 0 {
+			return data, false
+		}
+
+		key, ok := path[0], false
+		if _, ok = val[key]; !ok {
+			// encoding/json匹配字段名时不区分大小写
+			for k := range val {
+				if strings.EqualFold(k, key) {
+					key, ok = k, true
+					break
+				}
+			}
+		}
+		if !ok {
+			return data, false
+		}
+
+		item, changed := convertPath(val[key], path[1:], toNumber)
+		if changed {
+			val[key] = item
+		}
+		return data, changed
+	case []interface{}:
+		if len(path) > 0 {
+			if _, err := strconv.Atoi(path[0]); err == nil {
+				path = path[1:]
+			}
+		}
+
+		var changed bool
+		for i, item := range val {
+			if item, ok := convertPath(item, path, toNumber); ok {
+				val[i] = item
+				changed = true
+			}
+		}
+		return data, changed
+	}
+
+	if len(path) > 0 {
+		return data, false
+	}
+	return convertValue(data, toNumber)
+}
+
+// convertValue 将字符串转换为数字或将数字转换为字符串，空字符串转换为0
+func convertValue(data interface{}, toNumber bool) (interface{}, bool) {
+	switch v := data.(type) {
+	case string:
+		if !toNumber {
+			return data, false
+		}
+		if v = strings.TrimSpace(v); v == "" {
+			v = "0"
+		}
+		if _, err := strconv.ParseFloat(v, 64); err == nil {
+			return json.Number(v), true
+		}
+	case json.Number:
+		if !toNumber {
+			return v.String(), true
+		}
+	}
+	return data, false
+}
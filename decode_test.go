@@ -0,0 +1,142 @@
+package sdk
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+type decodeTestDept struct {
+	DeptID   int64  `json:"dept_id"`
+	ParentID int64  `json:"parent_id"`
+	Name     string `json:"name"`
+}
+
+type decodeTestResp struct {
+	CommonResp
+	Result struct {
+		DeptID     int64             `json:"dept_id"`
+		DeptIDList []int64           `json:"dept_id_list"`
+		UserID     string            `json:"userid"`
+		Depts      []*decodeTestDept `json:"depts"`
+		Remark     string            `json:"remark"`
+	} `json:"result"`
+}
+
+func TestDecodeResultFixesNumericFields(t *testing.T) {
+	cases := []struct {
+		name    string
+		payload string
+		check   func(resp *decodeTestResp) bool
+	}{
+		{
+			"string errcode",
+			`{"errcode":"0","errmsg":"ok"}`,
+			func(resp *decodeTestResp) bool { return resp.ErrCode == 0 && resp.ErrMsg == "ok" },
+		},
+		{
+			"string scalar",
+			`{"errcode":0,"result":{"dept_id":"12"}}`,
+			func(resp *decodeTestResp) bool { return resp.Result.DeptID == 12 },
+		},
+		{
+			"empty string scalar",
+			`{"errcode":0,"result":{"dept_id":""}}`,
+			func(resp *decodeTestResp) bool { return resp.Result.DeptID == 0 },
+		},
+		{
+			"number to string",
+			`{"errcode":0,"result":{"userid":10086}}`,
+			func(resp *decodeTestResp) bool { return resp.Result.UserID == "10086" },
+		},
+		{
+			"string array",
+			`{"errcode":0,"result":{"dept_id_list":["1","2"]}}`,
+			func(resp *decodeTestResp) bool { return fmt.Sprint(resp.Result.DeptIDList) == "[1 2]" },
+		},
+		{
+			"mixed array",
+			`{"errcode":0,"result":{"dept_id_list":[1,"2",3,"4","5","6","7","8","9","10"]}}`,
+			func(resp *decodeTestResp) bool {
+				return fmt.Sprint(resp.Result.DeptIDList) == "[1 2 3 4 5 6 7 8 9 10]"
+			},
+		},
+		{
+			"field in array of objects",
+			`{"errcode":0,"result":{"depts":[{"dept_id":1},{"dept_id":"2","parent_id":"1"}]}}`,
+			func(resp *decodeTestResp) bool {
+				depts := resp.Result.Depts
+				return len(depts) == 2 && depts[0].DeptID == 1 && depts[1].DeptID == 2 && depts[1].ParentID == 1
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var resp decodeTestResp
+			if err := decodeResult([]byte(c.payload), &resp); err != nil {
+				t.Fatalf("decodeResult: %v", err)
+			}
+			if !c.check(&resp) {
+				t.Errorf("decodeResult(%s) = %+v", c.payload, resp)
+			}
+		})
+	}
+}
+
+func TestFixNumericFieldOnlyFailedPath(t *testing.T) {
+	payload := []byte(`{"errcode":0,"result":{"dept_id":"12","name":"12","depts":[{"dept_id":1,"name":"7"}]}}`)
+
+	var out struct {
+		Result struct {
+			DeptID int64  `json:"dept_id"`
+			Name   string `json:"name"`
+		} `json:"result"`
+	}
+	err := json.Unmarshal(payload, &out)
+	fixed, ok := fixNumericField(payload, err)
+	if !ok {
+		t.Fatalf("fixNumericField(%v) failed", err)
+	}
+
+	var data map[string]interface{}
+	if err = json.Unmarshal(fixed, &data); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+
+	result := data["result"].(map[string]interface{})
+	if result["dept_id"] != 12.0 {
+		t.Errorf("dept_id = %#v, want 12", result["dept_id"])
+	}
+	// 错误路径以外的同名或其它字段保持不变
+	if result["name"] != "12" {
+		t.Errorf("name = %#v, want \"12\"", result["name"])
+	}
+	if name := result["depts"].([]interface{})[0].(map[string]interface{})["name"]; name != "7" {
+		t.Errorf("depts[0].name = %#v, want \"7\"", name)
+	}
+}
+
+func TestFixNumericFieldUnfixable(t *testing.T) {
+	cases := []string{
+		`{"result":{"dept_id":"abc"}}`,
+		`{"result":{"dept_id":true}}`,
+		`{"result":{"dept_id":{"id":1}}}`,
+	}
+
+	for _, payload := range cases {
+		var out struct {
+			Result struct {
+				DeptID int64 `json:"dept_id"`
+			} `json:"result"`
+		}
+		err := json.Unmarshal([]byte(payload), &out)
+		if _, ok := fixNumericField([]byte(payload), err); ok {
+			t.Errorf("fixNumericField(%s) 不应修正", payload)
+		}
+
+		if err = decodeResult([]byte(payload), &out); err == nil {
+			t.Errorf("decodeResult(%s) 应返回错误", payload)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package sdk
+
+import (
+	"sync"
+	"time"
+)
+
+// dedupCache 记录已经成功发送的机器人消息批次，用于幂等发送，记录在ttl后过期
+type dedupCache struct {
+	mutex   sync.Mutex
+	ttl     time.Duration
+	entries map[string]dedupEntry
+}
+
+type dedupEntry struct {
+	resp     *SendMsgByRobotResp
+	expireAt time.Time
+}
+
+func newDedupCache(ttl time.Duration) *dedupCache {
+	return &dedupCache{ttl: ttl, entries: make(map[string]dedupEntry)}
+}
+
+func (c *dedupCache) get(key string) (*SendMsgByRobotResp, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok || time.Now().After(entry.expireAt) {
+		return nil, false
+	}
+	return entry.resp, true
+}
+
+// put 记录发送结果，同时清理已经过期的记录，避免记录无限增长
+func (c *dedupCache) put(key string, resp *SendMsgByRobotResp) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	now := time.Now()
+	for k, entry := range c.entries {
+		if now.After(entry.expireAt) {
+			delete(c.entries, k)
+		}
+	}
+	c.entries[key] = dedupEntry{resp: resp, expireAt: now.Add(c.ttl)}
+}
@@ -1,96 +1,341 @@
 package sdk
 
 import (
-	"crypto/hmac"
-	"crypto/sha256"
-	"encoding/base64"
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"mime/multipart"
 	"net/http"
 	"net/url"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
+	"unicode/utf8"
 
 	logging "github.com/ipfs/go-log/v2"
+	"go.uber.org/multierr"
 )
 
 const (
-	domain             = "https://oapi.dingtalk.com"
-	reqAccessToken     = "/gettoken?appkey=%s&appsecret=%s"                               // 获取钉钉企业内部服务的access token
-	reqDept            = "/topapi/v2/department/listsub?access_token=%s"                  // 获取组织架构部门
-	reqChildrenDept    = "/topapi/v2/department/listsubid?access_token=%s"                // 获取子部门
-	reqUser            = "/topapi/user/listsimple?access_token=%s"                        // 获取部门下的用户(simple user)
-	reqUserDetail      = "/topapi/v2/user/list?access_token=%s"                           // 获取部门下用户的详细信息
-	reqApprovalProcess = "/topapi/processinstance/listids?access_token=%s"                // 获取指定审批流程清单
-	reqApprovalDetail  = "/topapi/processinstance/get?access_token=%s"                    // 获取审批流程详细信息
-	sendWorkNotify     = "/topapi/message/corpconversation/asyncsend_v2?access_token=%s"  // 发送工作通知
-	batchSendAPI       = "https://api.dingtalk.com/v1.0/robot/oToMessages/batchSend"      // 发送批量消息
-	reqProcessCode     = "/topapi/process/get_by_name?access_token=%s"                    // 获取模板code
-	snsReq             = "/sns/getuserinfo_bycode?accessKey=%s&timestamp=%s&signature=%s" // 根据sns临时授权码获取用户信息
-	reqUserByUnionID   = "/topapi/user/getbyunionid?access_token=%s"                      // 根据UnionID获取用户信息
+	defaultOApiBaseURL = "https://oapi.dingtalk.com"                                        // 钉钉开放平台旧版服务端API
+	defaultApiBaseURL  = "https://api.dingtalk.com"                                         // 钉钉开放平台新版服务端API(v1.0)
+	reqAccessToken     = "/gettoken?appkey=%s&appsecret=%s"                                 // 获取钉钉企业内部服务的access token
+	reqJSAPITicket     = "/get_jsapi_ticket?access_token=%s"                                // 获取jsapi_ticket
+	reqDept            = "/topapi/v2/department/listsub?access_token=%s"                    // 获取组织架构部门
+	reqDeptDetail      = "/topapi/v2/department/get?access_token=%s"                        // 获取部门详情
+	reqDeptCreate      = "/topapi/v2/department/create?access_token=%s"                     // 创建部门
+	reqDeptUpdate      = "/topapi/v2/department/update?access_token=%s"                     // 更新部门
+	reqDeptDelete      = "/topapi/v2/department/delete?access_token=%s"                     // 删除部门
+	reqChildrenDept    = "/topapi/v2/department/listsubid?access_token=%s"                  // 获取子部门
+	reqParentsByUser   = "/topapi/v2/department/listparentbyuser?access_token=%s"           // 获取指定用户的所有父部门列表
+	reqParentsByDept   = "/topapi/v2/department/listparentbydept?access_token=%s"           // 获取指定部门的所有父部门列表
+	reqUser            = "/topapi/user/listsimple?access_token=%s"                          // 获取部门下的用户(simple user)
+	reqUserIDList      = "/topapi/user/listid?access_token=%s"                              // 获取部门用户userid列表
+	reqUserDetail      = "/topapi/v2/user/list?access_token=%s"                             // 获取部门下用户的详细信息
+	reqUserGet         = "/topapi/v2/user/get?access_token=%s"                              // 根据userid获取用户详情
+	reqUserCreate      = "/topapi/v2/user/create?access_token=%s"                           // 创建用户
+	reqUserUpdate      = "/topapi/v2/user/update?access_token=%s"                           // 更新用户信息
+	reqUserDelete      = "/topapi/v2/user/delete?access_token=%s"                           // 删除用户
+	reqApprovalProcess = "/topapi/processinstance/listids?access_token=%s"                  // 获取指定审批流程清单
+	reqApprovalDetail  = "/topapi/processinstance/get?access_token=%s"                      // 获取审批流程详细信息
+	reqApprovalFileURL = "/topapi/processinstance/file/url/get?access_token=%s"             // 获取审批附件的下载地址
+	sendWorkNotify     = "/topapi/message/corpconversation/asyncsend_v2?access_token=%s"    // 发送工作通知
+	reqSendProgress    = "/topapi/message/corpconversation/getsendprogress?access_token=%s" // 获取工作通知消息的发送进度
+	reqSendResult      = "/topapi/message/corpconversation/getsendresult?access_token=%s"   // 获取工作通知消息的发送结果
+	reqRecallNotify    = "/topapi/message/corpconversation/recall?access_token=%s"          // 撤回工作通知消息
+	reqMediaUpload     = "/media/upload?access_token=%s&type=%s"                            // 上传媒体文件
+	calendarEventsAPI  = "/v1.0/calendar/users/%s/calendars/primary/events"                 // 创建日程
+	batchSendAPI       = "/v1.0/robot/oToMessages/batchSend"                                // 发送批量消息
+	userAccessTokenAPI = "/v1.0/oauth2/userAccessToken"                                     // 获取用户token
+	userSearchAPI      = "/v1.0/contact/users/search"                                       // 搜索用户userid
+	reqProcessCode     = "/topapi/process/get_by_name?access_token=%s"                      // 获取模板code
+	reqProcessList     = "/topapi/process/listbyuserid?access_token=%s"                     // 获取可见的审批模板
+	snsReq             = "/sns/getuserinfo_bycode?accessKey=%s&timestamp=%s&signature=%s"   // 根据sns临时授权码获取用户信息
+	reqUserByUnionID   = "/topapi/user/getbyunionid?access_token=%s"                        // 根据UnionID获取用户信息
+	reqUserByMobile    = "/topapi/v2/user/getbymobile?access_token=%s"                      // 根据手机号获取用户信息
+	reqUserCount       = "/topapi/user/count?access_token=%s"                               // 获取员工人数
+	reqInactiveUsers   = "/topapi/inactive/user/v2/get?access_token=%s"                     // 获取未登录钉钉的员工列表
+	reqAdminList       = "/topapi/user/listadmin?access_token=%s"                           // 获取管理员列表
+	reqAdminScope      = "/topapi/user/get_admin_scope?access_token=%s"                     // 获取管理员通讯录权限范围
+	reqAttendanceList  = "/attendance/list?access_token=%s"                                 // 获取打卡结果
+	reqRoleList        = "/topapi/role/list?access_token=%s"                                // 获取角色列表
+	reqRoleUsers       = "/topapi/role/simplelist?access_token=%s"                          // 获取指定角色的员工列表
+	reqExtContactList  = "/topapi/extcontact/list?access_token=%s"                          // 获取外部联系人列表
 )
 
-func NewDingTalkClient(agentId, appKey, appSecret string) *DingTalkClient {
-	return &DingTalkClient{
-		log:       logging.Logger("dingtalk"),
-		agentId:   agentId,
-		appKey:    appKey,
-		appSecret: appSecret,
-		mutex:     new(sync.Mutex),
+func NewDingTalkClient(agentId, appKey, appSecret string, opts ...Option) *DingTalkClient {
+	client := &DingTalkClient{
+		log:               logging.Logger("dingtalk"),
+		oapiBaseURL:       defaultOApiBaseURL,
+		apiBaseURL:        defaultApiBaseURL,
+		concurrency:       defaultConcurrency,
+		httpClient:        http.DefaultClient,
+		agentId:           agentId,
+		appKey:            appKey,
+		appSecret:         appSecret,
+		mutex:             new(sync.Mutex),
+		refreshMutex:      new(sync.Mutex),
+		ticketMutex:       new(sync.Mutex),
+		robotDedup:        newDedupCache(robotDedupTTL),
+		userAgent:         defaultUserAgent,
+		maxRetries:        maxRateLimitRetries,
+		tokenSafetyMargin: defaultTokenSafetyMargin,
+		retryable:         isRateLimitedError,
 	}
+	client.ctx, client.cancel = context.WithCancel(context.Background())
+
+	for _, opt := range opts {
+		opt(client)
+	}
+	client.applyProxy()
+	return client
 }
 
+// DingTalkClient 钉钉服务端API的客户端，可以被多个goroutine并发使用
+// 客户端的配置只能在NewDingTalkClient时通过Option设置，创建后不会再修改；运行中会变化的状态只有
+// access_token和jsapi_ticket的缓存，分别由mutex和ticketMutex保护，refreshMutex只用于合并并发的access_token刷新。
+// 通过Option传入的Logger、Hooks、Inspector和RetryableChecker会在多个goroutine中被同时调用，需要自行保证并发安全。
 type DingTalkClient struct {
-	log         *logging.ZapEventLogger
-	agentId     string
-	appKey      string
-	appSecret   string
-	accessToken string
-	expireTime  time.Time // 获取到access_token后计算得到的过期时间
-	mutex       *sync.Mutex
+	log               Logger
+	oapiBaseURL       string // 旧版API的服务地址，默认为https://oapi.dingtalk.com
+	apiBaseURL        string // 新版API的服务地址，默认为https://api.dingtalk.com
+	concurrency       int    // 批量查询时并发请求的数量
+	httpClient        *http.Client
+	proxyURL          *url.URL // WithProxy设置的代理，在所有Option执行后应用到httpClient
+	agentId           string
+	appKey            string
+	appSecret         string
+	accessToken       string
+	expireTime        time.Time     // 获取到access_token后计算得到的过期时间
+	tokenSafetyMargin time.Duration // access_token和jsapi_ticket提前过期的安全余量
+	mutex             *sync.Mutex
+	refreshMutex      *sync.Mutex // 保证同一时间只有一个goroutine请求access_token，重试等待期间不持有mutex
+	jsapiTicket       string
+	ticketExpireTime  time.Time        // 获取到jsapi_ticket后计算得到的过期时间
+	ticketMutex       *sync.Mutex      // jsapi_ticket的缓存与access_token分开加锁，避免刷新ticket时阻塞获取access_token
+	robotDedup        *dedupCache      // 机器人消息幂等发送的记录
+	hooks             Hooks            // 接口调用的观测回调，未设置时为nil
+	acceptLanguage    string           // 请求头中的Accept-Language，用于指定错误信息的语言
+	userAgent         string           // 请求头中的User-Agent，默认为defaultUserAgent
+	maxRetries        int              // 请求失败时最多重试的次数
+	retryable         func(error) bool // 判断请求失败后是否可以重试，默认只重试触发频率限制的请求
+	retryBaseDelay    time.Duration    // 重试退避的初始等待时间，为0时使用默认值
+	retryMaxDelay     time.Duration    // 重试退避的最大等待时间，为0时使用默认值
+	inspector         Inspector        // 请求发送前的检查回调，未设置时为nil
+	dryRun            bool             // 为true时只构造请求，不发送给钉钉
+
+	// ctx 客户端的生命周期，Close后被取消，后台任务需要监听ctx.Done()退出
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// Close 关闭客户端，停止所有后台任务并取消正在进行的请求，关闭后不能再调用钉钉接口，可以重复调用
+// 关闭后调用钉钉接口返回的错误可以通过errors.Is(err, ErrClientClosed)判断
+func (d *DingTalkClient) Close() error {
+	d.cancel()
+	return nil
+}
+
+// requestContext 合并调用方的ctx与客户端的生命周期，任意一个被取消时请求都会被取消
+func (d *DingTalkClient) requestContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if ctx == nil || ctx == context.Background() {
+		return context.WithCancel(d.ctx)
+	}
+
+	reqCtx, cancel := context.WithCancel(ctx)
+	go func() {
+		select {
+		case <-d.ctx.Done():
+			cancel()
+		case <-reqCtx.Done():
+		}
+	}()
+	return reqCtx, cancel
 }
 
 // GetAccessToken 在使用access_token时，请注意：
-//access_token的有效期为7200秒（2小时），有效期内重复获取会返回相同结果并自动续期，过期后获取会返回新的access_token。
-//开发者需要缓存access_token，用于后续接口的调用。因为每个应用的access_token是彼此独立的，所以进行缓存时需要区分应用来进行存储。
-//不能频繁调用gettoken接口，否则会受到频率拦截。
+// access_token的有效期为7200秒（2小时），有效期内重复获取会返回相同结果并自动续期，过期后获取会返回新的access_token。
+// 开发者需要缓存access_token，用于后续接口的调用。因为每个应用的access_token是彼此独立的，所以进行缓存时需要区分应用来进行存储。
+// 不能频繁调用gettoken接口，否则会受到频率拦截。
 func (d *DingTalkClient) GetAccessToken() (string, error) {
+	token, _, err := d.GetAccessTokenWithExpiry()
+	return token, err
+}
+
+// GetAccessTokenWithExpiry 与GetAccessToken相同，同时返回access_token的过期时间
+// 过期时间已经减去了安全余量(见WithTokenSafetyMargin)，调用方可据此安排刷新；dry-run模式下过期时间为零值
+func (d *DingTalkClient) GetAccessTokenWithExpiry() (string, time.Time, error) {
+	if d.dryRun {
+		return dryRunAccessToken, time.Time{}, nil
+	}
+
+	if token, expireTime, ok := d.cachedAccessToken(); ok {
+		return token, expireTime, nil
+	}
+
+	d.refreshMutex.Lock()
+	defer d.refreshMutex.Unlock()
+	// 等待期间其它goroutine可能已经刷新了access_token
+	if token, expireTime, ok := d.cachedAccessToken(); ok {
+		return token, expireTime, nil
+	}
+
+	token, err := d.refreshAccessToken()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return token, d.TokenExpiry(), nil
+}
+
+// cachedAccessToken 返回缓存中未过期的access_token
+func (d *DingTalkClient) cachedAccessToken() (string, time.Time, bool) {
 	d.mutex.Lock()
 	defer d.mutex.Unlock()
 	if d.accessToken != "" && time.Now().Before(d.expireTime) {
-		return d.accessToken, nil
+		return d.accessToken, d.expireTime, true
+	}
+	return "", time.Time{}, false
+}
+
+// ForceRefreshToken 忽略缓存，重新请求access_token
+func (d *DingTalkClient) ForceRefreshToken() (string, error) {
+	if d.dryRun {
+		return dryRunAccessToken, nil
+	}
+
+	d.refreshMutex.Lock()
+	defer d.refreshMutex.Unlock()
+	return d.refreshAccessToken()
+}
+
+// TokenExpiry 返回当前缓存的access_token的过期时间，尚未获取过access_token时返回零值
+// 过期时间已经减去了安全余量，见WithTokenSafetyMargin
+func (d *DingTalkClient) TokenExpiry() time.Time {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	return d.expireTime
+}
+
+// cacheExpiry 根据钉钉返回的有效期(秒)计算缓存的过期时间，提前tokenSafetyMargin过期，
+// 避免请求发出时access_token有效、到达钉钉时已经过期。有效期不足安全余量时按有效期的一半计算
+func (d *DingTalkClient) cacheExpiry(expiresIn int64) time.Time {
+	ttl := time.Duration(expiresIn) * time.Second
+	if d.tokenSafetyMargin < ttl {
+		ttl -= d.tokenSafetyMargin
+	} else {
+		ttl /= 2
+	}
+	return time.Now().Add(ttl)
+}
+
+// refreshAccessToken 请求新的access_token并更新缓存，调用方需持有d.refreshMutex
+// 请求失败时与其它接口使用相同的重试策略(WithMaxRetries、WithRetryBackoff、WithRetryableChecker)，
+// 重试等待期间不持有d.mutex，其它goroutine仍然可以读取缓存的access_token和过期时间
+func (d *DingTalkClient) refreshAccessToken() (string, error) {
+	backOff := d.newBackoff()
+	for retries := 0; ; retries++ {
+		if d.ctx.Err() != nil {
+			return "", ErrClientClosed
+		}
+
+		atr, wait, err := d.requestAccessToken()
+		if err != nil && retries < d.maxRetries && d.retryable(err) {
+			if err = d.waitRateLimit(d.ctx, backOff, retries, wait); err != nil {
+				return "", err
+			}
+			continue
+		}
+
+		if err != nil {
+			var dtErr *DingTalkError
+			if errors.As(err, &dtErr) {
+				d.mutex.Lock()
+				d.accessToken = ""
+				d.expireTime = time.Now()
+				d.mutex.Unlock()
+
+				// 频率限制等可重试的错误在重试耗尽后失败，与访问权限无关，不提示检查权限
+				if !d.retryable(err) {
+					return "", fmt.Errorf("请求access_token失败(Retries: %d): %w，请检查访问API权限", retries, err)
+				}
+			}
+			return "", fmt.Errorf("请求access_token失败(Retries: %d): %w", retries, err)
+		}
+
+		d.mutex.Lock()
+		d.accessToken = atr.AccessToken
+		d.expireTime = d.cacheExpiry(atr.ExpiresIn)
+		d.mutex.Unlock()
+
+		return atr.AccessToken, nil
+	}
+}
+
+// requestAccessToken 请求一次access_token，wait为响应头中Retry-After指定的等待时间
+// 返回的错误包括请求失败、HTTPError以及errcode不为0时的DingTalkError
+func (d *DingTalkClient) requestAccessToken() (atr *AccessTokenResp, wait time.Duration, err error) {
+	req, err := http.NewRequestWithContext(d.ctx, http.MethodGet, fmt.Sprintf(d.oapiBaseURL+reqAccessToken, d.appKey, d.appSecret), nil)
+	if err != nil {
+		return nil, 0, fmt.Errorf("创建HTTP请求失败: %w", err)
 	}
 
-	resp, err := http.Get(fmt.Sprintf(domain+reqAccessToken, d.appKey, d.appSecret))
+	// 与其他接口使用同一个http.Client，保证超时、代理等配置对获取access_token同样生效
+	resp, payload, err := d.do(req)
 	if err != nil {
-		return "", fmt.Errorf("请求access_token失败： %v", err)
+		return nil, 0, err
 	}
 
+	wait = retryAfter(resp.Header)
 	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("请求access_token失败: %s(%d)", resp.Status, resp.StatusCode)
+		return nil, wait, &HTTPError{StatusCode: resp.StatusCode, Status: resp.Status, Body: bodySnippet(payload)}
 	}
 
-	body := resp.Body
 	// Output: {"errcode":0,"access_token":"7122c6639d12378195cae4237d5fd61e","errmsg":"ok","expires_in":7200}
-	defer func() { _ = body.Close() }()
-	var atr AccessTokenResp
-	if err = readResult(body, &atr); err != nil {
-		return "", fmt.Errorf("读取access_token失败: %v", err)
+	atr = new(AccessTokenResp)
+	if err = decodeResult(payload, atr); err != nil {
+		return nil, wait, fmt.Errorf("读取access_token失败: %w", err)
 	}
 
 	if atr.ErrCode != 0 {
-		d.accessToken = ""
-		d.expireTime = time.Now()
-		return "", fmt.Errorf("请求access_token失败: %s(%d)，请检查访问API权限", atr.ErrMsg, atr.ErrCode)
+		return nil, wait, atr.toError()
+	}
+	return atr, wait, nil
+}
+
+// GetJSAPITicket 获取前端调用dd.config时计算签名所需的jsapi_ticket
+// jsapi_ticket的有效期为7200秒，有效期内返回缓存的结果，过期后重新获取
+func (d *DingTalkClient) GetJSAPITicket() (string, error) {
+	d.ticketMutex.Lock()
+	defer d.ticketMutex.Unlock()
+	if d.jsapiTicket != "" && time.Now().Before(d.ticketExpireTime) {
+		return d.jsapiTicket, nil
+	}
+
+	accToken, err := d.GetAccessToken()
+	if err != nil {
+		return "", err
+	}
+
+	reqUrl := fmt.Sprintf(d.oapiBaseURL+reqJSAPITicket, accToken)
+	var data JSAPITicketResp
+	if err = d.get(reqUrl, &data); err != nil {
+		return "", fmt.Errorf("请求jsapi_ticket失败: %w", err)
 	}
 
-	d.accessToken = atr.AccessToken
-	d.expireTime = time.Now().Add(time.Duration(atr.ExpiresIn) * time.Second)
+	if data.ErrCode != 0 {
+		d.jsapiTicket = ""
+		d.ticketExpireTime = time.Now()
+		return "", fmt.Errorf("请求jsapi_ticket失败: %w", data.toError())
+	}
 
-	return atr.AccessToken, nil
+	d.jsapiTicket = data.Ticket
+	d.ticketExpireTime = d.cacheExpiry(data.ExpiresIn)
+	return data.Ticket, nil
 }
 
 // GetDepartments 获取部门列表
@@ -106,428 +351,2103 @@ func (d *DingTalkClient) GetDepartments(deptID uint64, language Lang) (Departmen
 		lang = language
 	}
 
-	reqUrl := fmt.Sprintf(domain+reqDept, accToken)
+	reqUrl := fmt.Sprintf(d.oapiBaseURL+reqDept, accToken)
 	var data DepartmentResp
-	err = post(reqUrl, &DepartmentReq{
+	err = d.post(reqUrl, &DepartmentReq{
+		CommonDepartmentReq: CommonDepartmentReq{DeptID: deptID},
+		Language:            lang,
+	}, &data, nil)
+	if err != nil {
+		return nil, fmt.Errorf("请求部门(%d)清单失败: %w", deptID, err)
+	}
+
+	// Output: {"errcode":0,"errmsg":"ok","result":[{"auto_add_user":true,"create_dept_group":true,"dept_id":574367388,"name":"总经办","parent_id":1},{"auto_add_user":true,"create_dept_group":true,"dept_id":574545316,"name":"共","parent_id":1},{"auto_add_user":true,"create_dept_group":true,"dept_id":574575215,"name":"商务部","parent_id":1}],"request_id":"4uqsv89h1x82"}
+
+	if data.ErrCode != 0 {
+		return nil, fmt.Errorf("请求部门清单失败: %w", data.toError())
+	}
+	return data.Result, nil
+}
+
+// GetDepartmentsMulti 并发获取多个部门的下一级子部门，返回以父部门ID为键的子部门列表
+// 并发数为客户端配置的并发数，parentIDs中重复的ID只请求一次；任意一个部门获取失败时返回该错误
+func (d *DingTalkClient) GetDepartmentsMulti(parentIDs []uint64, language Lang) (map[uint64][]*DepartmentNameCnf, error) {
+	ids := make([]uint64, 0, len(parentIDs))
+	seen := make(map[uint64]struct{}, len(parentIDs))
+	for _, id := range parentIDs {
+		if _, ok := seen[id]; ok {
+			continue
+		}
+		seen[id] = struct{}{}
+		ids = append(ids, id)
+	}
+
+	var mutex sync.Mutex
+	result := make(map[uint64][]*DepartmentNameCnf, len(ids))
+	err := d.eachDept(ids, func(dept uint64) error {
+		depts, err := d.GetDepartments(dept, language)
+		if err != nil {
+			return err
+		}
+
+		mutex.Lock()
+		result[dept] = depts
+		mutex.Unlock()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// GetDepartmentDetail 获取单个部门的详细信息
+func (d *DingTalkClient) GetDepartmentDetail(deptID uint64, language Lang) (*DepartmentDetail, error) {
+	accToken, err := d.GetAccessToken()
+	if err != nil {
+		return nil, err
+	}
+
+	var lang = ChineseLanguage
+	if language == EnglishLanguage {
+		lang = language
+	}
+
+	reqUrl := fmt.Sprintf(d.oapiBaseURL+reqDeptDetail, accToken)
+	var data DepartmentDetailResp
+	err = d.post(reqUrl, &DepartmentReq{
 		CommonDepartmentReq: CommonDepartmentReq{DeptID: deptID},
 		Language:            lang,
 	}, &data, nil)
 	if err != nil {
-		return nil, fmt.Errorf("请求部门(%d)清单失败: %v", deptID, err)
+		return nil, fmt.Errorf("请求部门(%d)详情失败: %w", deptID, err)
+	}
+
+	if data.ErrCode != 0 {
+		return nil, fmt.Errorf("请求部门详情失败: %w", data.toError())
+	}
+	return data.Result, nil
+}
+
+// CreateDepartment 创建部门，返回新部门的ID
+func (d *DingTalkClient) CreateDepartment(reqParams CreateDeptReq) (uint64, error) {
+	accToken, err := d.GetAccessToken()
+	if err != nil {
+		return 0, err
+	}
+
+	reqUrl := fmt.Sprintf(d.oapiBaseURL+reqDeptCreate, accToken)
+	var data DepartmentCreateResp
+	if err = d.post(reqUrl, &reqParams, &data, nil); err != nil {
+		return 0, fmt.Errorf("创建部门(%s)失败: %w", reqParams.Name, err)
+	}
+
+	if data.ErrCode != 0 {
+		return 0, fmt.Errorf("创建部门失败: %w", data.toError())
+	}
+
+	if data.Result == nil {
+		return 0, nil
+	}
+	return data.Result.DeptID, nil
+}
+
+// UpdateDepartment 更新部门信息，只更新UpdateDeptReq中设置了的字段
+func (d *DingTalkClient) UpdateDepartment(reqParams UpdateDeptReq) error {
+	accToken, err := d.GetAccessToken()
+	if err != nil {
+		return err
+	}
+
+	reqUrl := fmt.Sprintf(d.oapiBaseURL+reqDeptUpdate, accToken)
+	var data CommonResp
+	if err = d.post(reqUrl, &reqParams, &data, nil); err != nil {
+		return fmt.Errorf("更新部门(%d)失败: %w", reqParams.DeptID, err)
+	}
+
+	if data.ErrCode != 0 {
+		return fmt.Errorf("更新部门失败: %w", data.toError())
+	}
+	return nil
+}
+
+// DeleteDepartment 删除部门，部门下存在员工或子部门时无法删除
+func (d *DingTalkClient) DeleteDepartment(deptID uint64) error {
+	accToken, err := d.GetAccessToken()
+	if err != nil {
+		return err
+	}
+
+	reqUrl := fmt.Sprintf(d.oapiBaseURL+reqDeptDelete, accToken)
+	var data CommonResp
+	if err = d.post(reqUrl, &CommonDepartmentReq{DeptID: deptID}, &data, nil); err != nil {
+		return fmt.Errorf("删除部门(%d)失败: %w", deptID, err)
+	}
+
+	if data.ErrCode != 0 {
+		return fmt.Errorf("删除部门失败: %w", data.toError())
+	}
+	return nil
+}
+
+func (d *DingTalkClient) GetChildrenDepartments(deptID uint64) ([]uint64, error) {
+	return d.GetChildrenDepartmentsWithContext(context.Background(), deptID)
+}
+
+// GetChildrenDepartmentsWithContext 获取下一级子部门ID列表，ctx取消后中止请求
+func (d *DingTalkClient) GetChildrenDepartmentsWithContext(ctx context.Context, deptID uint64) ([]uint64, error) {
+	accToken, err := d.GetAccessToken()
+	if err != nil {
+		return nil, err
+	}
+
+	reqUrl := fmt.Sprintf(d.oapiBaseURL+reqChildrenDept, accToken)
+	var data DepartmentChildrenResp
+	err = d.postContext(ctx, reqUrl, &DepartmentChildrenReq{CommonDepartmentReq{DeptID: deptID}}, &data, nil)
+	if err != nil {
+		return nil, fmt.Errorf("请求子部门(%d)清单失败: %w", deptID, err)
+	}
+
+	if data.ErrCode != 0 {
+		return nil, fmt.Errorf("请求子部门清单失败: %w", data.toError())
+	}
+
+	if data.Result == nil {
+		return nil, nil
+	}
+
+	return data.Result.DeptIDList, nil
+}
+
+// GetParentDepartmentsByUser 获取员工所在的全部部门及其父部门
+// 员工可能属于多个部门，每个部门返回一条路径，路径从员工所在部门开始依次向上直到根部门
+func (d *DingTalkClient) GetParentDepartmentsByUser(userid string) ([][]uint64, error) {
+	accToken, err := d.GetAccessToken()
+	if err != nil {
+		return nil, err
+	}
+
+	reqUrl := fmt.Sprintf(d.oapiBaseURL+reqParentsByUser, accToken)
+	var data ParentDeptByUserResp
+	if err = d.post(reqUrl, &UserGetReq{UserID: userid}, &data, nil); err != nil {
+		return nil, fmt.Errorf("请求员工(%s)的父部门列表失败: %w", userid, err)
+	}
+
+	if data.ErrCode != 0 {
+		return nil, fmt.Errorf("请求员工父部门列表失败: %w", data.toError())
+	}
+
+	if data.Result == nil {
+		return nil, nil
+	}
+
+	paths := make([][]uint64, 0, len(data.Result.ParentList))
+	for _, item := range data.Result.ParentList {
+		paths = append(paths, item.ParentDeptIDList)
+	}
+	return paths, nil
+}
+
+// GetParentDepartmentsByDept 获取部门的全部父部门ID，从该部门开始依次向上直到根部门
+func (d *DingTalkClient) GetParentDepartmentsByDept(deptID uint64) ([]uint64, error) {
+	accToken, err := d.GetAccessToken()
+	if err != nil {
+		return nil, err
+	}
+
+	reqUrl := fmt.Sprintf(d.oapiBaseURL+reqParentsByDept, accToken)
+	var data ParentDeptByDeptResp
+	if err = d.post(reqUrl, &CommonDepartmentReq{DeptID: deptID}, &data, nil); err != nil {
+		return nil, fmt.Errorf("请求部门(%d)的父部门列表失败: %w", deptID, err)
+	}
+
+	if data.ErrCode != 0 {
+		return nil, fmt.Errorf("请求部门父部门列表失败: %w", data.toError())
+	}
+
+	if data.Result == nil {
+		return nil, nil
+	}
+	return data.Result.ParentIDList, nil
+}
+
+func (d *DingTalkClient) GetSimpleUsers(reqParams SimpleUserReq) (*ListSimpleUserRes, error) {
+	return d.GetSimpleUsersWithContext(context.Background(), reqParams)
+}
+
+// GetSimpleUsersWithContext 与GetSimpleUsers相同，ctx取消后中止请求
+func (d *DingTalkClient) GetSimpleUsersWithContext(ctx context.Context, reqParams SimpleUserReq) (*ListSimpleUserRes, error) {
+	accToken, err := d.GetAccessToken()
+	if err != nil {
+		return nil, err
+	}
+
+	reqUrl := fmt.Sprintf(d.oapiBaseURL+reqUser, accToken)
+	var data SimpleUserResp
+	err = d.postContext(ctx, reqUrl, &reqParams, &data, nil)
+	if err != nil {
+		return nil, fmt.Errorf("请求部门下(%d)的员工基本信息失败: %w", reqParams.DeptID, err)
+	}
+
+	if data.ErrCode != 0 {
+		return nil, fmt.Errorf("请求部门员工基本信息失败; %w", data.toError())
+	}
+
+	return data.Result, nil
+}
+
+func (d *DingTalkClient) GetUsers(reqParams SimpleUserReq) (*ListUserDetailRes, error) {
+	return d.GetUsersWithContext(context.Background(), reqParams)
+}
+
+// GetUsersWithContext 与GetUsers相同，ctx取消后中止请求
+func (d *DingTalkClient) GetUsersWithContext(ctx context.Context, reqParams SimpleUserReq) (*ListUserDetailRes, error) {
+	accToken, err := d.GetAccessToken()
+	if err != nil {
+		return nil, err
+	}
+
+	reqUrl := fmt.Sprintf(d.oapiBaseURL+reqUserDetail, accToken)
+	var data UserDetailResp
+	err = d.postContext(ctx, reqUrl, &reqParams, &data, nil)
+	if err != nil {
+		return nil, fmt.Errorf("请求部门（%d）下的员工详细信息失败: %w", reqParams.DeptID, err)
+	}
+
+	if data.ErrCode != 0 {
+		return nil, fmt.Errorf("请求部门员工详细信息失败; %w", data.toError())
+	}
+
+	return data.Result, nil
+}
+
+// GetDeptUserIDs 获取部门下的员工userid列表
+// 该接口一次返回部门下全部员工的userid，不需要翻页，比GetSimpleUsers开销更小
+func (d *DingTalkClient) GetDeptUserIDs(deptID uint64) ([]string, error) {
+	accToken, err := d.GetAccessToken()
+	if err != nil {
+		return nil, err
+	}
+
+	reqUrl := fmt.Sprintf(d.oapiBaseURL+reqUserIDList, accToken)
+	var data UserIDListResp
+	err = d.post(reqUrl, &CommonDepartmentReq{DeptID: deptID}, &data, nil)
+	if err != nil {
+		return nil, fmt.Errorf("请求部门(%d)员工userid列表失败: %w", deptID, err)
+	}
+
+	if data.ErrCode != 0 {
+		return nil, fmt.Errorf("请求部门员工userid列表失败: %w", data.toError())
+	}
+
+	if data.Result == nil {
+		return nil, nil
+	}
+
+	return data.Result.UserIDList, nil
+}
+
+// GetDepartmentUserCount 获取部门的直属员工人数，不包含子部门的员工
+// 钉钉没有按部门统计人数的接口，这里通过GetDeptUserIDs获取userid列表后计数，无需拉取员工详情
+func (d *DingTalkClient) GetDepartmentUserCount(deptID uint64) (int, error) {
+	ids, err := d.GetDeptUserIDs(deptID)
+	if err != nil {
+		return 0, err
+	}
+	return len(ids), nil
+}
+
+// GetUserDetail 根据userid获取用户的详细信息
+func (d *DingTalkClient) GetUserDetail(userid string, language Lang) (*DingDingUser, error) {
+	accToken, err := d.GetAccessToken()
+	if err != nil {
+		return nil, err
+	}
+
+	var lang = ChineseLanguage
+	if language == EnglishLanguage {
+		lang = language
+	}
+
+	reqUrl := fmt.Sprintf(d.oapiBaseURL+reqUserGet, accToken)
+	var data UserGetResp
+	err = d.post(reqUrl, &UserGetReq{UserID: userid, Language: lang}, &data, nil)
+	if err != nil {
+		return nil, fmt.Errorf("请求员工(%s)详细信息失败: %w", userid, err)
+	}
+
+	if data.ErrCode != 0 {
+		return nil, fmt.Errorf("请求员工详细信息失败: %w", data.toError())
+	}
+
+	return data.Result, nil
+}
+
+// CreateUser 创建员工，返回新员工的userid
+func (d *DingTalkClient) CreateUser(reqParams CreateUserReq) (string, error) {
+	if err := reqParams.validate(); err != nil {
+		return "", err
+	}
+
+	accToken, err := d.GetAccessToken()
+	if err != nil {
+		return "", err
+	}
+
+	reqUrl := fmt.Sprintf(d.oapiBaseURL+reqUserCreate, accToken)
+	var data UserCreateResp
+	if err = d.post(reqUrl, &reqParams, &data, nil); err != nil {
+		return "", fmt.Errorf("创建员工(%s)失败: %w", reqParams.Name, err)
+	}
+
+	if data.ErrCode != 0 {
+		return "", fmt.Errorf("创建员工失败: %w", data.toError())
+	}
+
+	if data.Result == nil {
+		return "", nil
+	}
+	return data.Result.UserID, nil
+}
+
+// UpdateUser 更新员工信息，只更新UpdateUserReq中设置了的字段
+func (d *DingTalkClient) UpdateUser(reqParams UpdateUserReq) error {
+	if reqParams.UserID == "" {
+		return fmt.Errorf("更新员工信息时userid不能为空")
+	}
+
+	accToken, err := d.GetAccessToken()
+	if err != nil {
+		return err
+	}
+
+	reqUrl := fmt.Sprintf(d.oapiBaseURL+reqUserUpdate, accToken)
+	var data CommonResp
+	if err = d.post(reqUrl, &reqParams, &data, nil); err != nil {
+		return fmt.Errorf("更新员工(%s)信息失败: %w", reqParams.UserID, err)
+	}
+
+	if data.ErrCode != 0 {
+		return fmt.Errorf("更新员工信息失败: %w", data.toError())
+	}
+	return nil
+}
+
+// DeleteUser 删除员工
+func (d *DingTalkClient) DeleteUser(userid string) error {
+	accToken, err := d.GetAccessToken()
+	if err != nil {
+		return err
+	}
+
+	reqUrl := fmt.Sprintf(d.oapiBaseURL+reqUserDelete, accToken)
+	var data CommonResp
+	if err = d.post(reqUrl, &UserGetReq{UserID: userid}, &data, nil); err != nil {
+		return fmt.Errorf("删除员工(%s)失败: %w", userid, err)
+	}
+
+	if data.ErrCode != 0 {
+		return fmt.Errorf("删除员工失败: %w", data.toError())
+	}
+	return nil
+}
+
+// GetUsersByIDs 并发获取一组userid对应的用户详细信息
+// 钉钉没有按userid批量查询的接口，这里通过并发调用GetUserDetail实现，concurrency为并发数，
+// 小于等于0时使用客户端配置的并发数。查询失败的userid不会出现在返回结果中，其错误会合并后返回。
+func (d *DingTalkClient) GetUsersByIDs(userIDs []string, language Lang, concurrency int) (map[string]*DingDingUser, error) {
+	var mutex sync.Mutex
+	users := make(map[string]*DingDingUser, len(userIDs))
+	err := d.eachID(userIDs, concurrency, func(id string) error {
+		user, err := d.GetUserDetail(id, language)
+		if err != nil {
+			return err
+		}
+
+		if user != nil {
+			mutex.Lock()
+			users[id] = user
+			mutex.Unlock()
+		}
+		return nil
+	})
+
+	return users, err
+}
+
+// GetDepartmentsByParent 递归获取指定部门下全部子孙部门的ID，返回结果已去重
+func (d *DingTalkClient) GetDepartmentsByParent(ids ...uint64) ([]uint64, error) {
+	return d.GetDepartmentsByParentWithContext(context.Background(), ids...)
+}
+
+// GetDepartmentsByParentWithContext 递归获取指定部门下全部子孙部门的ID，返回结果已去重
+// 部门树较大时可以通过ctx取消整个遍历过程。某个部门获取失败(如没有权限)时跳过该部门继续遍历，
+// 返回已获取到的部门以及合并后的错误；ctx取消时停止遍历。
+func (d *DingTalkClient) GetDepartmentsByParentWithContext(ctx context.Context, ids ...uint64) ([]uint64, error) {
+	var data []uint64
+	seen := make(map[uint64]struct{})
+	err := d.collectDepartments(ctx, ids, seen, &data)
+	return data, err
+}
+
+// collectDepartments 深度优先遍历部门树，seen中记录已经遍历过的部门，避免重复请求和重复返回
+func (d *DingTalkClient) collectDepartments(ctx context.Context, ids []uint64, seen map[uint64]struct{}, data *[]uint64) error {
+	var errs error
+	for _, deptId := range ids {
+		if err := ctx.Err(); err != nil {
+			// 子部门的遍历可能已经记录了ctx的错误
+			if !errors.Is(errs, err) {
+				errs = multierr.Append(errs, err)
+			}
+			return errs
+		}
+
+		children, err := d.GetChildrenDepartmentsWithContext(ctx, deptId)
+		if err != nil {
+			errs = multierr.Append(errs, fmt.Errorf("部门(%d): %w", deptId, err))
+			continue
+		}
+
+		var unseen []uint64
+		for _, child := range children {
+			if _, ok := seen[child]; !ok {
+				seen[child] = struct{}{}
+				unseen = append(unseen, child)
+			}
+		}
+
+		if len(unseen) > 0 {
+			errs = multierr.Append(errs, d.collectDepartments(ctx, unseen, seen, data))
+		}
+		*data = append(*data, unseen...)
+	}
+	return errs
+}
+
+// GetDepartmentsRecursive 递归获取指定部门下全部子孙部门的基础信息，返回结果已去重
+// 直接使用每一层GetDepartments返回的部门信息，不需要再按ID逐个查询部门名称
+func (d *DingTalkClient) GetDepartmentsRecursive(deptID uint64, language Lang) (DepartmentNameCnfCollection, error) {
+	var data DepartmentNameCnfCollection
+	seen := map[uint64]struct{}{deptID: {}}
+	queue := []uint64{deptID}
+	for len(queue) > 0 {
+		parent := queue[0]
+		queue = queue[1:]
+
+		children, err := d.GetDepartments(parent, language)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, child := range children {
+			if _, ok := seen[child.DeptID]; ok {
+				continue
+			}
+
+			seen[child.DeptID] = struct{}{}
+			data = append(data, child)
+			queue = append(queue, child.DeptID)
+		}
+	}
+	return data, nil
+}
+
+// GetDepartmentUserTree 获取以rootDeptID为根的部门树，每个部门节点附带该部门的直属员工
+// 先获取整棵部门树，再并发获取各部门的员工，并发数可通过WithConcurrency设置
+func (d *DingTalkClient) GetDepartmentUserTree(rootDeptID uint64) (*DingDingDeptNode, error) {
+	root, err := d.GetDepartmentDetail(rootDeptID, ChineseLanguage)
+	if err != nil {
+		return nil, err
+	}
+
+	depts, err := d.GetDepartmentsRecursive(rootDeptID, ChineseLanguage)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := []uint64{rootDeptID}
+	children := make(map[uint64][]*DepartmentNameCnf)
+	for _, dept := range depts {
+		ids = append(ids, dept.DeptID)
+		children[dept.ParentID] = append(children[dept.ParentID], dept)
+	}
+
+	var mutex sync.Mutex
+	users := make(map[uint64][]*SimpleUser, len(ids))
+	err = d.eachDept(ids, func(dept uint64) error {
+		var list []*SimpleUser
+		it := d.IterateSimpleUsers(dept)
+		for user, ok := it.Next(); ok; user, ok = it.Next() {
+			list = append(list, user)
+		}
+		if err := it.Err(); err != nil {
+			return err
+		}
+
+		mutex.Lock()
+		users[dept] = list
+		mutex.Unlock()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var build func(info DingDingDeptInfo) DingDingDeptNode
+	build = func(info DingDingDeptInfo) DingDingDeptNode {
+		node := DingDingDeptNode{Info: info, Users: users[info.DeptID]}
+		for _, child := range children[info.DeptID] {
+			node.Children = append(node.Children, build(DingDingDeptInfo{DeptID: child.DeptID, Name: child.Name, PID: child.ParentID}))
+		}
+		return node
+	}
+
+	info := DingDingDeptInfo{DeptID: rootDeptID}
+	if root != nil {
+		info.Name, info.PID = root.Name, root.ParentID
+	}
+
+	tree := build(info)
+	return &tree, nil
+}
+
+func (d *DingTalkClient) GetDepartmentNamesByParent(ids ...uint64) ([]uint64, error) {
+	var data []uint64
+	for _, deptId := range ids {
+		children, err := d.GetChildrenDepartments(deptId)
+		if err != nil {
+			return nil, fmt.Errorf("%v, %w", ids, err)
+		}
+
+		if len(children) > 0 {
+			cc, err := d.GetDepartmentsByParent(children...)
+			if err != nil {
+				return nil, fmt.Errorf("%v, %w", children, err)
+			}
+
+			data = append(data, cc...)
+		}
+		data = append(data, children...)
+	}
+	return data, nil
+}
+
+// GetSimpleUserByDeptIDList 获取多个部门下的员工基本信息，并按userid去重
+// 各部门之间并发请求，并发数可通过WithConcurrency设置
+func (d *DingTalkClient) GetSimpleUserByDeptIDList(depts []uint64, language Lang) ([]*SimpleUser, error) {
+	users, _, err := d.GetSimpleUserByDeptIDListWithPage(depts, language, PageParams{Size: maxPageSize})
+	if err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// GetSimpleUserByDeptIDListWithPage 与GetSimpleUserByDeptIDList相同，可以指定每页数量以及各部门的起始游标(page.Cursors)
+// 同时返回各部门最后请求的分页游标，保存后作为下次查询的Cursors即可继续同步；从该游标继续时最多重复获取一页，结果按userid去重。
+// 某个部门查询失败时返回已获取到的员工、各部门的游标以及错误
+func (d *DingTalkClient) GetSimpleUserByDeptIDListWithPage(depts []uint64, language Lang, page PageParams) ([]*SimpleUser, map[uint64]int, error) {
+	return d.GetSimpleUserByDeptIDListWithContext(context.Background(), depts, language, page)
+}
+
+// GetSimpleUserByDeptIDListWithContext 与GetSimpleUserByDeptIDListWithPage相同，整个翻页汇总过程受ctx控制，
+// ctx超时或取消后停止请求剩余的分页和部门，并返回ctx.Err()及各部门的游标
+func (d *DingTalkClient) GetSimpleUserByDeptIDListWithContext(ctx context.Context, depts []uint64, language Lang, page PageParams) ([]*SimpleUser, map[uint64]int, error) {
+	if err := page.validate(); err != nil {
+		return nil, nil, err
+	}
+
+	var lang = ChineseLanguage
+	if language == EnglishLanguage {
+		lang = language
+	}
+
+	var mutex sync.Mutex
+	users := make(map[string]*SimpleUser)
+	cursors := make(map[uint64]int, len(depts))
+	err := d.eachDept(depts, func(dept uint64) error {
+		cursor := page.Cursors[dept]
+		for {
+			mutex.Lock()
+			cursors[dept] = cursor
+			mutex.Unlock()
+
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			listRes, err := d.GetSimpleUsersWithContext(ctx, SimpleUserReq{
+				CommonDepartmentReq: CommonDepartmentReq{DeptID: dept},
+				Cursor:              cursor,
+				Size:                page.Size,
+				OrderField:          EntryAsc,
+				ContainAccessLimit:  false,
+				Language:            lang,
+			})
+
+			if err != nil {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				return err
+			}
+
+			cursor = listRes.NextCursor
+			mutex.Lock()
+			for _, u := range listRes.List {
+				users[u.UserID] = u
+			}
+			mutex.Unlock()
+
+			if !listRes.HasMore {
+				return nil
+			}
+		}
+	})
+
+	data := make([]*SimpleUser, 0, len(users))
+	for _, item := range users {
+		data = append(data, item)
+	}
+	return data, cursors, err
+}
+
+// GetUsersByDeptIDList 获取多个部门下的员工详细信息，并按userid去重
+// 各部门之间并发请求，并发数可通过WithConcurrency设置
+func (d *DingTalkClient) GetUsersByDeptIDList(depts []uint64, language Lang) ([]*DingDingUser, error) {
+	users, _, err := d.GetUsersByDeptIDListWithPage(depts, language, PageParams{Size: maxPageSize})
+	if err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// GetUsersByDeptIDListWithPage 与GetUsersByDeptIDList相同，分页参数及返回的各部门游标见GetSimpleUserByDeptIDListWithPage
+func (d *DingTalkClient) GetUsersByDeptIDListWithPage(depts []uint64, language Lang, page PageParams) ([]*DingDingUser, map[uint64]int, error) {
+	return d.GetUsersByDeptIDListWithContext(context.Background(), depts, language, page)
+}
+
+// GetUsersByDeptIDListWithContext 与GetUsersByDeptIDListWithPage相同，整个翻页汇总过程受ctx控制，
+// ctx超时或取消后停止请求剩余的分页和部门，并返回ctx.Err()及各部门的游标
+func (d *DingTalkClient) GetUsersByDeptIDListWithContext(ctx context.Context, depts []uint64, language Lang, page PageParams) ([]*DingDingUser, map[uint64]int, error) {
+	if err := page.validate(); err != nil {
+		return nil, nil, err
+	}
+
+	var lang = ChineseLanguage
+	if language == EnglishLanguage {
+		lang = language
+	}
+
+	var mutex sync.Mutex
+	users := make(map[string]*DingDingUser)
+	cursors := make(map[uint64]int, len(depts))
+	err := d.eachDept(depts, func(dept uint64) error {
+		cursor := page.Cursors[dept]
+		for {
+			mutex.Lock()
+			cursors[dept] = cursor
+			mutex.Unlock()
+
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			listRes, err := d.GetUsersWithContext(ctx, SimpleUserReq{
+				CommonDepartmentReq: CommonDepartmentReq{DeptID: dept},
+				Cursor:              cursor,
+				Size:                page.Size,
+				OrderField:          EntryAsc,
+				ContainAccessLimit:  false,
+				Language:            lang,
+			})
+
+			if err != nil {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				return err
+			}
+
+			cursor = listRes.NextCursor
+			mutex.Lock()
+			for _, u := range listRes.List {
+				users[u.UserID] = u
+			}
+			mutex.Unlock()
+
+			if !listRes.HasMore {
+				return nil
+			}
+		}
+	})
+
+	data := make([]*DingDingUser, 0, len(users))
+	for _, item := range users {
+		data = append(data, item)
+	}
+	return data, cursors, err
+}
+
+// eachID 使用concurrency个goroutine并发地对每个id执行fn，concurrency小于等于0时使用d.concurrency
+// 所有id都会被处理，各id返回的错误合并后返回
+func (d *DingTalkClient) eachID(ids []string, concurrency int, fn func(id string) error) error {
+	if concurrency <= 0 {
+		concurrency = d.concurrency
+	}
+
+	var (
+		wg    sync.WaitGroup
+		mutex sync.Mutex
+		errs  error
+		queue = make(chan string)
+	)
+
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for id := range queue {
+				if err := fn(id); err != nil {
+					mutex.Lock()
+					errs = multierr.Append(errs, err)
+					mutex.Unlock()
+				}
+			}
+		}()
+	}
+
+	for _, id := range ids {
+		queue <- id
+	}
+	close(queue)
+	wg.Wait()
+
+	return errs
+}
+
+// eachDept 使用d.concurrency个goroutine并发地对每个部门执行fn
+// 任意一个部门返回错误后不再处理剩余的部门，并返回第一个错误
+func (d *DingTalkClient) eachDept(depts []uint64, fn func(dept uint64) error) error {
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+		done     = make(chan struct{})
+		queue    = make(chan uint64)
+	)
+
+	for i := 0; i < d.concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for dept := range queue {
+				if err := fn(dept); err != nil {
+					once.Do(func() {
+						firstErr = err
+						close(done)
+					})
+				}
+			}
+		}()
+	}
+
+dispatch:
+	for _, dept := range depts {
+		select {
+		case queue <- dept:
+		case <-done:
+			break dispatch
+		}
+	}
+	close(queue)
+	wg.Wait()
+
+	return firstErr
+}
+
+func (d *DingTalkClient) GetApprovalProcessIDList(params ApprovalProcessIDReq) (*ApprovalProcessRes, error) {
+	accToken, err := d.GetAccessToken()
+	if err != nil {
+		return nil, err
+	}
+
+	reqUrl := fmt.Sprintf(d.oapiBaseURL+reqApprovalProcess, accToken)
+	var data ApprovalProcessIDListResp
+	err = d.post(reqUrl, &params, &data, nil)
+	if err != nil {
+		return nil, fmt.Errorf("请求审批流程(%s)失败: %w", params.ProcessCode, err)
+	}
+
+	//fmt.Println(data)
+	if data.ErrCode != 0 {
+		return nil, fmt.Errorf("请求审批流程失败; %w", data.toError())
+	}
+
+	return data.Result, nil
+}
+
+// GetAllApprovalProcessIDs 按游标翻页获取时间范围内全部的审批实例ID
+func (d *DingTalkClient) GetAllApprovalProcessIDs(params ApprovalProcessIDReq) ([]string, error) {
+	var data []string
+	for {
+		res, err := d.GetApprovalProcessIDList(params)
+		if err != nil {
+			return nil, err
+		}
+
+		if res == nil {
+			break
+		}
+
+		data = append(data, res.List...)
+		// 没有下一页时钉钉不再返回next_cursor
+		if res.NextCursor == 0 || res.NextCursor == params.Cursor {
+			break
+		}
+		params.Cursor = res.NextCursor
+	}
+	return data, nil
+}
+
+func (d *DingTalkClient) GetApprovalDetail(processID string) (*ApprovalDetail, error) {
+	accToken, err := d.GetAccessToken()
+	if err != nil {
+		return nil, err
+	}
+
+	reqUrl := fmt.Sprintf(d.oapiBaseURL+reqApprovalDetail, accToken)
+	var data ApprovalDetailResp
+	err = d.post(reqUrl, &ApprovalDetailReq{ProcessInstanceID: processID}, &data, nil)
+	if err != nil {
+		return nil, fmt.Errorf("请求审批详情(%s)失败: %w", processID, err)
+	}
+
+	if data.ErrCode != 0 {
+		return nil, fmt.Errorf("请求审批详情失败: %w", data.toError())
+	}
+
+	return data.Detail, nil
+}
+
+// GetApprovalFileURL 获取审批实例中附件的下载地址，fileID为ApprovalAttachment.FileID
+// 调用前需要在审批实例所在应用中开通钉盘相关权限，返回的下载地址有效期较短
+func (d *DingTalkClient) GetApprovalFileURL(processID, fileID string) (*ApprovalFileURL, error) {
+	accToken, err := d.GetAccessToken()
+	if err != nil {
+		return nil, err
+	}
+
+	reqObj := new(ApprovalFileURLReq)
+	reqObj.Request.ProcessInstanceID = processID
+	reqObj.Request.FileID = fileID
+
+	reqUrl := fmt.Sprintf(d.oapiBaseURL+reqApprovalFileURL, accToken)
+	var data ApprovalFileURLResp
+	if err = d.post(reqUrl, reqObj, &data, nil); err != nil {
+		return nil, fmt.Errorf("请求审批附件(%s)下载地址失败: %w", fileID, err)
+	}
+
+	if data.ErrCode != 0 {
+		return nil, fmt.Errorf("请求审批附件下载地址失败: %w", data.toError())
+	}
+
+	return data.Result, nil
+}
+
+// WaitApprovalDetail 轮询审批实例详情，直到审批结束后返回，结束的判断见ApprovalDetail.IsFinished
+// 除NEW和RUNNING以外的状态都会返回，包括COMPLETED、TERMINATED、CANCELED以及钉钉新增的未知状态，调用方需要自行检查Status。
+// 刚创建或刚审批完成的实例，详情接口可能短时间内返回尚未更新的数据，可以使用该方法等待数据一致。
+// interval为轮询间隔，小于等于0时为1秒；ctx超时或取消时返回最后一次获取到的详情及ctx的错误。
+func (d *DingTalkClient) WaitApprovalDetail(ctx context.Context, processID string, interval time.Duration) (*ApprovalDetail, error) {
+	if interval <= 0 {
+		interval = time.Second
+	}
+
+	for {
+		detail, err := d.GetApprovalDetail(processID)
+		if err != nil {
+			return nil, err
+		}
+
+		if detail != nil && detail.IsFinished() {
+			return detail, nil
+		}
+
+		timer := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return detail, ctx.Err()
+		case <-d.ctx.Done():
+			timer.Stop()
+			return detail, ErrClientClosed
+		case <-timer.C:
+		}
+	}
+}
+
+// GetApprovalComments 获取审批实例中的全部评论，包括审批时填写的意见以及单独添加的评论(ADD_REMARK)
+// 钉钉没有单独分页查询评论的接口，评论随审批详情中的操作记录一次返回，这里从操作记录中筛选出有评论内容的记录
+func (d *DingTalkClient) GetApprovalComments(instanceId string) ([]*ApprovalOperationRecord, error) {
+	detail, err := d.GetApprovalDetail(instanceId)
+	if err != nil {
+		return nil, err
+	}
+
+	if detail == nil {
+		return nil, nil
+	}
+
+	var comments []*ApprovalOperationRecord
+	for _, record := range detail.OperationRecords {
+		if record.Remark != "" || record.OperationType == "ADD_REMARK" {
+			comments = append(comments, record)
+		}
+	}
+	return comments, nil
+}
+
+// GetApprovalDetails 并发获取多个审批实例的详情，并发数为客户端配置的并发数
+// 获取失败的实例不会出现在返回结果中，各实例的错误会合并后返回
+func (d *DingTalkClient) GetApprovalDetails(ids []string) (map[string]*ApprovalDetail, error) {
+	var mutex sync.Mutex
+	details := make(map[string]*ApprovalDetail, len(ids))
+	err := d.eachID(ids, d.concurrency, func(id string) error {
+		detail, err := d.GetApprovalDetail(id)
+		if err != nil {
+			return err
+		}
+
+		if detail != nil {
+			mutex.Lock()
+			details[id] = detail
+			mutex.Unlock()
+		}
+		return nil
+	})
+
+	return details, err
+}
+
+// GetApprovalsInRange 获取指定审批模板在时间范围内发起的全部审批实例详情
+// 先翻页获取全部审批实例ID，再并发获取每个实例的详情，返回结果与实例ID的顺序一致。
+// 获取失败的实例不会出现在返回结果中，各实例的错误会合并后返回。
+func (d *DingTalkClient) GetApprovalsInRange(processCode string, start, end time.Time) ([]*ApprovalDetail, error) {
+	ids, err := d.GetAllApprovalProcessIDs(NewApprovalProcessIDReq(processCode, start, end))
+	if err != nil {
+		return nil, err
+	}
+
+	details, err := d.GetApprovalDetails(ids)
+	data := make([]*ApprovalDetail, 0, len(details))
+	for _, id := range ids {
+		if detail, ok := details[id]; ok {
+			data = append(data, detail)
+		}
+	}
+	return data, err
+}
+
+// GetApprovalProcessInstancesByBusinessID 查找指定审批模板在时间范围内审批编号(business_id)为businessID的审批实例
+// 钉钉没有按审批编号查询的接口，这里需要先通过listids获取时间范围内的全部实例ID，再逐个获取详情后比对，
+// 实例较多时耗时较长，应尽量缩小时间范围。获取失败的实例会被跳过，各实例的错误会合并后返回。
+func (d *DingTalkClient) GetApprovalProcessInstancesByBusinessID(processCode, businessID string, start, end time.Time) ([]*ApprovalDetail, error) {
+	details, err := d.GetApprovalsInRange(processCode, start, end)
+
+	var data []*ApprovalDetail
+	for _, detail := range details {
+		if detail.BusinessID == businessID {
+			data = append(data, detail)
+		}
+	}
+	return data, err
+}
+
+// SendMessageFromRobot 通过机器人发送官方markdown格式的单聊消息
+func (d *DingTalkClient) SendMessageFromRobot(robotCode, title, content string, to []string) (*SendMsgByRobotResp, error) {
+	return d.SendRobotMessage(robotCode, &MsgContent{Title: title, Text: content}, to)
+}
+
+// SendRobotMessage 通过机器人批量发送单聊消息，消息类型由msg决定
+// 钉钉每次最多发送给20个用户，超过20个接收人时会分批发送，并合并各批次的结果。
+// 未指定接收人时返回ErrNoRecipients；某一批次发送失败时，返回已发送批次的结果及错误。
+func (d *DingTalkClient) SendRobotMessage(robotCode string, msg Message, to []string) (*SendMsgByRobotResp, error) {
+	return d.sendRobotMessage("", robotCode, msg, to)
+}
+
+// SendRobotMessageIdempotent 与SendRobotMessage相同，使用调用方指定的幂等键key避免重复发送
+// 同一个key下已经发送成功的批次会被记录robotDedupTTL时间，期间使用相同的key重新调用时(如上次部分批次失败后重试)，
+// 已成功的批次直接返回记录的结果而不会再次发送。相同的key不能在多个goroutine中同时使用。
+func (d *DingTalkClient) SendRobotMessageIdempotent(key, robotCode string, msg Message, to []string) (*SendMsgByRobotResp, error) {
+	if key == "" {
+		return nil, fmt.Errorf("幂等键不能为空")
+	}
+	return d.sendRobotMessage(key, robotCode, msg, to)
+}
+
+func (d *DingTalkClient) sendRobotMessage(key, robotCode string, msg Message, to []string) (*SendMsgByRobotResp, error) {
+	if robotCode == "" {
+		return nil, ErrEmptyRobotCode
+	}
+
+	if len(to) == 0 {
+		return nil, ErrNoRecipients
+	}
+
+	// 提前编码一次消息，在请求钉钉之前发现无效的消息
+	if _, err := json.Marshal(msg); err != nil {
+		return nil, fmt.Errorf("生成消息失败: %w", err)
+	}
+
+	accToken, err := d.GetAccessToken()
+	if err != nil {
+		return nil, err
+	}
+
+	header := http.Header{"x-acs-dingtalk-access-token": []string{accToken}}
+	var ret SendMsgByRobotResp
+	for start := 0; start < len(to); start += robotBatchSize {
+		end := start + robotBatchSize
+		if end > len(to) {
+			end = len(to)
+		}
+
+		batchKey := ""
+		if key != "" {
+			batchKey = fmt.Sprintf("%s#%d", key, start)
+			if batch, ok := d.robotDedup.get(batchKey); ok {
+				ret.merge(batch)
+				continue
+			}
+		}
+
+		batch, err := d.sendRobotBatch(&SendMsgByRobotReq{
+			RobotCode: robotCode,
+			UserIDs:   to[start:end],
+			MsgKey:    msg.MsgKey(),
+			MsgParam:  msg,
+		}, header)
+		if err != nil {
+			return &ret, fmt.Errorf("发送第%d~%d个接收人的消息失败: %w", start+1, end, err)
+		}
+
+		if batchKey != "" {
+			d.robotDedup.put(batchKey, batch)
+		}
+		ret.merge(batch)
+	}
+
+	return &ret, nil
+}
+
+// SendLocalizedRobotMessage 按接收人的语言发送不同版本的机器人消息
+// msgs为各语言的消息，to为接收人userid及其语言；接收人的语言没有对应的消息时使用fallback语言的消息。
+// 相同语言的接收人一起分批发送，返回合并后的结果；某种语言发送失败时，返回已发送的结果及错误。
+func (d *DingTalkClient) SendLocalizedRobotMessage(robotCode string, msgs map[Lang]Message, to map[string]Lang, fallback Lang) (*SendMsgByRobotResp, error) {
+	if len(to) == 0 {
+		return nil, ErrNoRecipients
+	}
+
+	if _, ok := msgs[fallback]; !ok {
+		return nil, fmt.Errorf("缺少默认语言(%s)的消息", fallback)
+	}
+
+	groups := make(map[Lang][]string)
+	for userID, lang := range to {
+		if _, ok := msgs[lang]; !ok {
+			lang = fallback
+		}
+		groups[lang] = append(groups[lang], userID)
+	}
+
+	langs := make([]Lang, 0, len(groups))
+	for lang := range groups {
+		langs = append(langs, lang)
+	}
+	sort.Slice(langs, func(i, j int) bool { return langs[i] < langs[j] })
+
+	var ret SendMsgByRobotResp
+	for _, lang := range langs {
+		users := groups[lang]
+		sort.Strings(users)
+		res, err := d.SendRobotMessage(robotCode, msgs[lang], users)
+		if res != nil {
+			ret.merge(res)
+		}
+
+		if err != nil {
+			return &ret, fmt.Errorf("发送%s消息失败: %w", lang, err)
+		}
+	}
+	return &ret, nil
+}
+
+// sendRobotBatch 发送一个批次的机器人消息，失败时按照客户端的退避策略等待后重试，最多请求d.maxRetries+1次且不超过robotMaxAttempts次
+// 每次发送都不经过post内部的重试，返回错误中的Attempts即为实际的请求次数
+// 只有钉钉明确返回触发频率限制(HTTP 429或频率限制的errcode)时才重试，此时消息一定没有被发送；
+// 网络超时、5xx等错误无法确定消息是否已经送达，为避免重复通知接收人直接返回错误，也不使用WithRetryableChecker的判断
+func (d *DingTalkClient) sendRobotBatch(reqObj *SendMsgByRobotReq, header http.Header) (*SendMsgByRobotResp, error) {
+	var (
+		err     error
+		wait    time.Duration
+		backOff = d.newBackoff()
+	)
+
+	attempts := d.maxRetries + 1
+	if attempts > robotMaxAttempts {
+		attempts = robotMaxAttempts
+	}
+
+	retries := 0
+	for ; ; retries++ {
+		var ret SendMsgByRobotResp
+		if wait, err = d.sendOnce(context.Background(), d.apiBaseURL+batchSendAPI, reqObj, &ret, header); err == nil {
+			return &ret, nil
+		}
+
+		if retries+1 >= attempts || !isRateLimitedError(err) {
+			break
+		}
+
+		d.log.Errorf("第%d次发送消息失败: %v", retries+1, err)
+		if waitErr := d.waitRateLimit(context.Background(), backOff, retries, wait); waitErr != nil {
+			return nil, waitErr
+		}
+	}
+
+	return nil, fmt.Errorf("发送批量消息接口失败(Attempts: %d): %w", retries+1, err)
+}
+
+// ResolveRobotRecipients 将机器人发送结果中无效和被限流的userid解析为员工姓名，便于在告警中展示
+// 每个userid都需要调用一次GetUserDetail，因此需要显式调用。language为姓名使用的语言，无法查询到的userid姓名为空，其错误会合并后返回。
+func (d *DingTalkClient) ResolveRobotRecipients(resp *SendMsgByRobotResp, language Lang) (*RobotRecipientReport, error) {
+	report := new(RobotRecipientReport)
+	if resp == nil {
+		return report, nil
+	}
+
+	ids := make([]string, 0, len(resp.InvalidStaffIdList)+len(resp.FlowControlledStaffIdList))
+	ids = append(ids, resp.InvalidStaffIdList...)
+	ids = append(ids, resp.FlowControlledStaffIdList...)
+	if len(ids) == 0 {
+		return report, nil
+	}
+
+	users, err := d.GetUsersByIDs(ids, language, 0)
+	resolve := func(ids []string) []*RobotRecipient {
+		data := make([]*RobotRecipient, 0, len(ids))
+		for _, id := range ids {
+			item := &RobotRecipient{UserID: id}
+			if user, ok := users[id]; ok {
+				item.Name = user.Name
+			}
+			data = append(data, item)
+		}
+		return data
+	}
+
+	report.Invalid = resolve(resp.InvalidStaffIdList)
+	report.FlowControlled = resolve(resp.FlowControlledStaffIdList)
+	return report, err
+}
+
+// GetProcessCode 根据审批模板名称获取模板的process_code
+// 模板不存在时返回的错误可以通过errors.Is(err, ErrProcessNotFound)判断
+func (d *DingTalkClient) GetProcessCode(name string) (string, error) {
+	accToken, err := d.GetAccessToken()
+	if err != nil {
+		return "", err
+	}
+	reqUrl := fmt.Sprintf(d.oapiBaseURL+reqProcessCode, accToken)
+
+	var data ProcessCodeResult
+	err = d.post(reqUrl, &ProcessCodeReq{Name: name}, &data, nil)
+	if err != nil {
+		return "", fmt.Errorf("请求模版(%s)Code失败: %w", name, err)
+	}
+
+	if data.ErrCode != 0 {
+		return "", fmt.Errorf("请求模版Code失败: %w", data.toError())
+	}
+
+	if data.Code == "" {
+		return "", fmt.Errorf("%w: %s", ErrProcessNotFound, name)
+	}
+
+	return data.Code, nil
+}
+
+// GetProcessTemplates 获取审批模板列表及其process_code，自动翻页直到获取完毕
+// userid为空时返回企业全部的审批模板，否则返回该员工可见的审批模板
+func (d *DingTalkClient) GetProcessTemplates(userid string) ([]*ProcessTemplate, error) {
+	accToken, err := d.GetAccessToken()
+	if err != nil {
+		return nil, err
+	}
+
+	reqUrl := fmt.Sprintf(d.oapiBaseURL+reqProcessList, accToken)
+	reqObj := &ProcessListReq{UserID: userid, Offset: 0, Size: maxPageSize}
+	var templates []*ProcessTemplate
+	for {
+		var data ProcessListResp
+		if err = d.post(reqUrl, reqObj, &data, nil); err != nil {
+			return nil, fmt.Errorf("请求审批模板列表失败: %w", err)
+		}
+
+		if data.ErrCode != 0 {
+			return nil, fmt.Errorf("请求审批模板列表失败: %w", data.toError())
+		}
+
+		if data.Result == nil {
+			return templates, nil
+		}
+
+		templates = append(templates, data.Result.ProcessList...)
+		// 没有下一页时不返回next_cursor
+		if data.Result.NextCursor == nil {
+			return templates, nil
+		}
+		reqObj.Offset = *data.Result.NextCursor
+	}
+}
+
+// SendWorkNotify 以当前应用(或reqParams.AgentID指定的应用)的身份发送工作通知，返回异步发送任务的task_id
+// 发送为异步过程，可通过GetSendProgress和GetSendResult查询发送进度与结果
+func (d *DingTalkClient) SendWorkNotify(reqParams WorkNotifyReq) (int64, error) {
+	agentID, err := d.resolveAgentID(reqParams.AgentID)
+	if err != nil {
+		return 0, err
+	}
+
+	if err = reqParams.validate(); err != nil {
+		return 0, err
+	}
+
+	accToken, err := d.GetAccessToken()
+	if err != nil {
+		return 0, err
+	}
+
+	reqUrl := fmt.Sprintf(d.oapiBaseURL+sendWorkNotify, accToken)
+	var data WorkNotifyResp
+	err = d.post(reqUrl, &workNotifyReq{AgentID: agentID, WorkNotifyReq: reqParams}, &data, nil)
+	if err != nil {
+		return 0, fmt.Errorf("发送工作通知失败: %w", err)
+	}
+
+	if data.ErrCode != 0 {
+		return 0, fmt.Errorf("发送工作通知失败: %w", data.toError())
+	}
+
+	return data.TaskID, nil
+}
+
+// GetSendProgress 获取工作通知消息的发送进度
+// agentId为发送该消息的应用，为空时使用创建客户端时指定的agentId
+func (d *DingTalkClient) GetSendProgress(agentId string, taskId int64) (*WorkNotifyProgress, error) {
+	agentID, err := d.resolveAgentID(agentId)
+	if err != nil {
+		return nil, err
+	}
+
+	accToken, err := d.GetAccessToken()
+	if err != nil {
+		return nil, err
+	}
+
+	reqUrl := fmt.Sprintf(d.oapiBaseURL+reqSendProgress, accToken)
+	var data WorkNotifyProgressResp
+	err = d.post(reqUrl, &WorkNotifyTaskReq{AgentID: agentID, TaskID: taskId}, &data, nil)
+	if err != nil {
+		return nil, fmt.Errorf("请求工作通知(%d)发送进度失败: %w", taskId, err)
+	}
+
+	if data.ErrCode != 0 {
+		return nil, fmt.Errorf("请求工作通知发送进度失败: %w", data.toError())
+	}
+
+	return data.Progress, nil
+}
+
+// GetSendResult 获取工作通知消息的发送结果，agentId的含义与GetSendProgress相同
+func (d *DingTalkClient) GetSendResult(agentId string, taskId int64) (*WorkNotifySendResult, error) {
+	agentID, err := d.resolveAgentID(agentId)
+	if err != nil {
+		return nil, err
+	}
+
+	accToken, err := d.GetAccessToken()
+	if err != nil {
+		return nil, err
+	}
+
+	reqUrl := fmt.Sprintf(d.oapiBaseURL+reqSendResult, accToken)
+	var data WorkNotifySendResultResp
+	err = d.post(reqUrl, &WorkNotifyTaskReq{AgentID: agentID, TaskID: taskId}, &data, nil)
+	if err != nil {
+		return nil, fmt.Errorf("请求工作通知(%d)发送结果失败: %w", taskId, err)
+	}
+
+	if data.ErrCode != 0 {
+		return nil, fmt.Errorf("请求工作通知发送结果失败: %w", data.toError())
+	}
+
+	return data.SendResult, nil
+}
+
+// RecallWorkNotify 撤回已发送的工作通知消息，只能撤回24小时内发送的消息，agentId的含义与GetSendProgress相同
+func (d *DingTalkClient) RecallWorkNotify(agentId string, taskId int64) error {
+	agentID, err := d.resolveAgentID(agentId)
+	if err != nil {
+		return err
+	}
+
+	accToken, err := d.GetAccessToken()
+	if err != nil {
+		return err
+	}
+
+	reqUrl := fmt.Sprintf(d.oapiBaseURL+reqRecallNotify, accToken)
+	var data CommonResp
+	err = d.post(reqUrl, &WorkNotifyRecallReq{AgentID: agentID, MsgTaskID: taskId}, &data, nil)
+	if err != nil {
+		return fmt.Errorf("撤回工作通知(%d)失败: %w", taskId, err)
+	}
+
+	if data.ErrCode != 0 {
+		return fmt.Errorf("撤回工作通知失败: %w", data.toError())
+	}
+
+	return nil
+}
+
+func (d *DingTalkClient) GetUserIDFromScanQrCode(tmpCode string) (string, error) {
+	user, err := d.GetUserFromScanQrCode(tmpCode)
+	if err != nil {
+		return "", err
+	}
+
+	return user.UserID, nil
+}
+
+// GetUserFromScanQrCode 根据扫码登录的临时授权码获取用户信息
+// 返回结果同时包含用户的userid以及扫码时获取到的昵称、unionid、openid，无需再次查询
+func (d *DingTalkClient) GetUserFromScanQrCode(tmpCode string) (*ScanQrCodeUser, error) {
+	snsUserInfo, err := d.GetUserUnionIDByCode(tmpCode)
+	if err != nil {
+		return nil, err
+	}
+
+	if snsUserInfo == nil {
+		return nil, fmt.Errorf("无效的UnionID")
+	}
+
+	res, err := d.GetUserByUnionID(snsUserInfo.UnionID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ScanQrCodeUser{
+		SnsUserInfo: *snsUserInfo,
+		UserID:      res.UserID,
+		ContactType: res.ContactType,
+	}, nil
+}
+
+func (d *DingTalkClient) GetUserUnionIDByCode(tmpCode string) (*SnsUserInfo, error) {
+
+	// 根据钉钉OpenAPI设定，通过钉钉扫码登陆过后拿到的临时登陆码换取用户信息步骤如下：
+	// 参考：https://open.dingtalk.com/document/orgapp-server/obtain-the-user-information-based-on-the-sns-temporary-authorization
+	// 1. 准备三个参数：accessKey (为应用的AppKey，在开发者后台应用详情页查看。)
+	// 2. timestamp （当前时间戳，单位毫秒。）
+	// 3. 对timestamp做签名后的结果（该结果为HashMacSha256->Base64编码->urlencode编码）
+	ts := time.Now().UnixNano() / 1000000
+	timestamp := strconv.FormatInt(ts, 10)
+	sig := SignTimestamp(d.appSecret, ts)
+
+	reqUrl := fmt.Sprintf(d.oapiBaseURL+snsReq, d.appKey, timestamp, sig)
+	// 请求地址中包含签名，临时授权码也属于敏感信息，均不输出到日志中
+	d.log.Debugf("根据sns临时授权码获取用户信息, accessKey: %s, timestamp: %s", d.appKey, timestamp)
+	var data SnsResponse
+	err := d.post(reqUrl, &SnsRequest{TmpAuthCode: tmpCode}, &data, nil)
+	if err != nil {
+		return nil, fmt.Errorf("根据sns临时授权码获取用户信息失败: %w", err)
+	}
+
+	if data.ErrCode > 0 {
+		return nil, data.toError()
+	}
+
+	return data.UserInfo, nil
+}
+
+// GetUserAccessToken 使用用户授权后得到的authCode换取用户的access_token，用于以用户身份调用新版接口
+// 使用客户端的AppKey和AppSecret作为clientId和clientSecret
+func (d *DingTalkClient) GetUserAccessToken(authCode string) (*UserAccessToken, error) {
+	return d.requestUserAccessToken(&UserAccessTokenReq{
+		ClientID:     d.appKey,
+		ClientSecret: d.appSecret,
+		Code:         authCode,
+		GrantType:    "authorization_code",
+	})
+}
+
+// RefreshUserAccessToken 使用refreshToken刷新用户的access_token
+func (d *DingTalkClient) RefreshUserAccessToken(refreshToken string) (*UserAccessToken, error) {
+	return d.requestUserAccessToken(&UserAccessTokenReq{
+		ClientID:     d.appKey,
+		ClientSecret: d.appSecret,
+		RefreshToken: refreshToken,
+		GrantType:    "refresh_token",
+	})
+}
+
+func (d *DingTalkClient) requestUserAccessToken(reqObj *UserAccessTokenReq) (*UserAccessToken, error) {
+	var data UserAccessTokenResp
+	if err := d.post(d.apiBaseURL+userAccessTokenAPI, reqObj, &data, nil); err != nil {
+		return nil, fmt.Errorf("请求用户access_token失败: %w", err)
+	}
+
+	return &UserAccessToken{
+		AccessToken:  data.AccessToken,
+		RefreshToken: data.RefreshToken,
+		ExpireTime:   time.Now().Add(time.Duration(data.ExpireIn) * time.Second),
+		CorpID:       data.CorpID,
+	}, nil
+}
+
+// GetAttendanceList 获取员工的打卡结果
+// 查询的考勤日期跨度不能超过7天，每次最多查询50个员工，Limit最大为50
+func (d *DingTalkClient) GetAttendanceList(reqParams AttendanceListReq) ([]*AttendanceRecord, bool, error) {
+	if len(reqParams.UserIDList) == 0 || len(reqParams.UserIDList) > 50 {
+		return nil, false, fmt.Errorf("查询打卡结果的员工数量须在1~50之间, 当前%d个", len(reqParams.UserIDList))
+	}
+
+	if reqParams.Limit <= 0 || reqParams.Limit > 50 {
+		reqParams.Limit = 50
+	}
+
+	accToken, err := d.GetAccessToken()
+	if err != nil {
+		return nil, false, err
+	}
+
+	reqUrl := fmt.Sprintf(d.oapiBaseURL+reqAttendanceList, accToken)
+	var data AttendanceListResp
+	err = d.post(reqUrl, &reqParams, &data, nil)
+	if err != nil {
+		return nil, false, fmt.Errorf("请求打卡结果(%s ~ %s)失败: %w", reqParams.WorkDateFrom, reqParams.WorkDateTo, err)
+	}
+
+	if data.ErrCode != 0 {
+		return nil, false, fmt.Errorf("请求打卡结果失败: %w", data.toError())
+	}
+
+	return data.RecordResult, data.HasMore, nil
+}
+
+// GetUserIDByUnionID 根据unionid获取用户userid
+func (d *DingTalkClient) GetUserIDByUnionID(unionID string) (userId string, err error) {
+	res, err := d.GetUserByUnionID(unionID)
+	if err != nil {
+		return "", err
+	}
+
+	return res.UserID, nil
+}
+
+// GetUserByUnionID 根据unionid获取用户userid及联系类型
+func (d *DingTalkClient) GetUserByUnionID(unionID string) (*UserGetByUnionIdResponse, error) {
+	accToken, err := d.GetAccessToken()
+	if err != nil {
+		return nil, err
+	}
+
+	reqUrl := fmt.Sprintf(d.oapiBaseURL+reqUserByUnionID, accToken)
+	var data UserIDResponse
+	if err = d.post(reqUrl, &UserIDReq{UnionID: unionID}, &data, nil); err != nil {
+		return nil, err
+	}
+
+	if data.ErrCode > 0 {
+		return nil, data.toError()
+	}
+
+	if data.Result == nil {
+		return nil, fmt.Errorf("unionid(%s)对应的用户不存在", unionID)
+	}
+
+	return data.Result, nil
+}
+
+// GetUserIDByMobile 根据手机号获取用户userid
+// 开启了专属帐号时，返回结果中还包含该手机号对应的专属帐号userid列表
+func (d *DingTalkClient) GetUserIDByMobile(mobile string) (*UserGetByMobileResponse, error) {
+	accToken, err := d.GetAccessToken()
+	if err != nil {
+		return nil, err
 	}
 
-	// Output: {"errcode":0,"errmsg":"ok","result":[{"auto_add_user":true,"create_dept_group":true,"dept_id":574367388,"name":"总经办","parent_id":1},{"auto_add_user":true,"create_dept_group":true,"dept_id":574545316,"name":"共","parent_id":1},{"auto_add_user":true,"create_dept_group":true,"dept_id":574575215,"name":"商务部","parent_id":1}],"request_id":"4uqsv89h1x82"}
+	reqUrl := fmt.Sprintf(d.oapiBaseURL+reqUserByMobile, accToken)
+	var data UserIDByMobileResponse
+	err = d.post(reqUrl, &UserIDByMobileReq{Mobile: mobile, SupportExclusiveAccountSearch: true}, &data, nil)
+	if err != nil {
+		return nil, fmt.Errorf("根据手机号获取员工userid失败: %w", err)
+	}
 
 	if data.ErrCode != 0 {
-		return nil, fmt.Errorf("请求部门清单失败: %s(%d)", data.ErrMsg, data.ErrCode)
+		return nil, fmt.Errorf("根据手机号获取员工userid失败: %w", data.toError())
 	}
+
 	return data.Result, nil
 }
 
-func (d *DingTalkClient) GetChildrenDepartments(deptID uint64) ([]uint64, error) {
+// GetUserCount 获取企业员工人数，onlyActive为true时只统计激活了钉钉的员工
+func (d *DingTalkClient) GetUserCount(onlyActive bool) (int, error) {
 	accToken, err := d.GetAccessToken()
 	if err != nil {
-		return nil, err
+		return 0, err
 	}
 
-	reqUrl := fmt.Sprintf(domain+reqChildrenDept, accToken)
-	var data DepartmentChildrenResp
-	err = post(reqUrl, &DepartmentChildrenReq{CommonDepartmentReq{DeptID: deptID}}, &data, nil)
-	if err != nil {
-		return nil, fmt.Errorf("请求子部门(%d)清单失败: %v", deptID, err)
+	reqUrl := fmt.Sprintf(d.oapiBaseURL+reqUserCount, accToken)
+	var data UserCountResp
+	if err = d.post(reqUrl, &UserCountReq{OnlyActive: onlyActive}, &data, nil); err != nil {
+		return 0, fmt.Errorf("请求员工人数失败: %w", err)
 	}
 
 	if data.ErrCode != 0 {
-		return nil, fmt.Errorf("请求子部门清单失败: %s(%d)", data.ErrMsg, data.ErrCode)
+		return 0, fmt.Errorf("请求员工人数失败: %w", data.toError())
 	}
 
 	if data.Result == nil {
-		return nil, nil
+		return 0, nil
 	}
 
-	return data.Result.DeptIDList, nil
+	return data.Result.Count, nil
 }
 
-func (d *DingTalkClient) GetSimpleUsers(reqParams SimpleUserReq) (*ListSimpleUserRes, error) {
-	accToken, err := d.GetAccessToken()
+// GetInactiveUsers 获取指定日期未登录(未激活)钉钉的员工userid列表，自动翻页直到获取完毕
+// deptIDs为空时查询全部员工，否则只查询指定部门(最多1000个)的员工
+func (d *DingTalkClient) GetInactiveUsers(date time.Time, deptIDs []uint64) ([]string, error) {
+	ids, err := d.getUsersByLoginStatus(date, deptIDs, false)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("请求未登录钉钉的员工列表失败: %w", err)
 	}
+	return ids, nil
+}
 
-	reqUrl := fmt.Sprintf(domain+reqUser, accToken)
-	var data SimpleUserResp
-	err = post(reqUrl, &reqParams, &data, nil)
+// GetActiveUsers 获取指定日期登录过钉钉的员工userid列表，参数与GetInactiveUsers相同
+func (d *DingTalkClient) GetActiveUsers(date time.Time, deptIDs []uint64) ([]string, error) {
+	ids, err := d.getUsersByLoginStatus(date, deptIDs, true)
 	if err != nil {
-		return nil, fmt.Errorf("请求部门下(%d)的员工基本信息失败: %v", reqParams.DeptID, err)
+		return nil, fmt.Errorf("请求登录钉钉的员工列表失败: %w", err)
 	}
+	return ids, nil
+}
 
-	if data.ErrCode != 0 {
-		return nil, fmt.Errorf("请求部门员工基本信息失败; %s(%d)", data.ErrMsg, data.ErrCode)
+// GetActiveUserStatistics 统计截止到date(含)的日活跃和周活跃员工数
+// 钉钉没有直接返回活跃人数的接口(/topapi/report/statistics为日志的统计数据)，这里通过查询每天登录过钉钉的员工计算：
+// 日活跃为date当天登录过的员工数，周活跃为date及之前6天内登录过的去重员工数。需要逐天分页查询，员工较多时耗时较长。
+func (d *DingTalkClient) GetActiveUserStatistics(date time.Time, deptIDs []uint64) (*ActiveUserStatistics, error) {
+	stat := &ActiveUserStatistics{Date: date.Format(InactiveDateLayout)}
+	weekly := make(map[string]struct{})
+	for i := 0; i < 7; i++ {
+		ids, err := d.GetActiveUsers(date.AddDate(0, 0, -i), deptIDs)
+		if err != nil {
+			return nil, err
+		}
+
+		if i == 0 {
+			stat.DailyActive = len(ids)
+		}
+		for _, id := range ids {
+			weekly[id] = struct{}{}
+		}
 	}
 
-	return data.Result, nil
+	stat.WeeklyActive = len(weekly)
+	return stat, nil
 }
 
-func (d *DingTalkClient) GetUsers(reqParams SimpleUserReq) (*ListUserDetailRes, error) {
+// getUsersByLoginStatus 按是否登录过钉钉查询指定日期的员工userid列表，自动翻页直到获取完毕
+func (d *DingTalkClient) getUsersByLoginStatus(date time.Time, deptIDs []uint64, isActive bool) ([]string, error) {
 	accToken, err := d.GetAccessToken()
 	if err != nil {
 		return nil, err
 	}
 
-	reqUrl := fmt.Sprintf(domain+reqUserDetail, accToken)
-	var data UserDetailResp
-	err = post(reqUrl, &reqParams, &data, nil)
-	if err != nil {
-		return nil, fmt.Errorf("请求部门（%d）下的员工详细信息失败: %v", reqParams.DeptID, err)
-	}
-
-	if data.ErrCode != 0 {
-		return nil, fmt.Errorf("请求部门员工详细信息失败; %s(%d)", data.ErrMsg, data.ErrCode)
+	reqUrl := fmt.Sprintf(d.oapiBaseURL+reqInactiveUsers, accToken)
+	reqObj := &InactiveUserReq{
+		IsActive:  isActive,
+		DeptIDs:   deptIDs,
+		Offset:    0,
+		Size:      maxPageSize,
+		QueryDate: date.Format(InactiveDateLayout),
 	}
 
-	return data.Result, nil
-}
+	var ids []string
+	for {
+		var data InactiveUserResp
+		if err = d.post(reqUrl, reqObj, &data, nil); err != nil {
+			return nil, err
+		}
 
-func (d *DingTalkClient) GetDepartmentsByParent(ids ...uint64) ([]uint64, error) {
-	var data []uint64
-	for _, deptId := range ids {
-		children, err := d.GetChildrenDepartments(deptId)
-		if err != nil {
-			return nil, fmt.Errorf("%v, %v", ids, err)
+		if data.ErrCode != 0 {
+			return nil, data.toError()
 		}
 
-		if len(children) > 0 {
-			cc, err := d.GetDepartmentsByParent(children...)
-			if err != nil {
-				return nil, fmt.Errorf("%v, %v", children, err)
-			}
+		if data.Result == nil {
+			return ids, nil
+		}
 
-			data = append(data, cc...)
+		ids = append(ids, data.Result.List...)
+		if !data.Result.HasMore {
+			return ids, nil
 		}
-		data = append(data, children...)
+		reqObj.Offset = data.Result.NextCursor
 	}
-	return data, nil
 }
 
-func (d *DingTalkClient) GetDepartmentNamesByParent(ids ...uint64) ([]uint64, error) {
-	var data []uint64
-	for _, deptId := range ids {
-		children, err := d.GetChildrenDepartments(deptId)
-		if err != nil {
-			return nil, fmt.Errorf("%v, %v", ids, err)
-		}
+// SearchUsers 按关键词(姓名、拼音等)搜索员工，返回匹配的userid列表以及是否还有更多结果
+// 钉钉的用户搜索接口为新版API(/v1.0/contact/users/search)，size取值1~100，offset为结果的偏移量
+func (d *DingTalkClient) SearchUsers(keyword string, offset, size int) ([]string, bool, error) {
+	if err := validatePageSize(size); err != nil {
+		return nil, false, err
+	}
 
-		if len(children) > 0 {
-			cc, err := d.GetDepartmentsByParent(children...)
-			if err != nil {
-				return nil, fmt.Errorf("%v, %v", children, err)
-			}
+	if offset < 0 {
+		return nil, false, fmt.Errorf("搜索结果的偏移量(%d)无效", offset)
+	}
 
-			data = append(data, cc...)
-		}
-		data = append(data, children...)
+	accToken, err := d.GetAccessToken()
+	if err != nil {
+		return nil, false, err
 	}
-	return data, nil
-}
 
-func (d *DingTalkClient) GetSimpleUserByDeptIDList(depts []uint64) ([]*SimpleUser, error) {
-	users := make(map[string]*SimpleUser)
-	for _, dept := range depts {
-		cursor := 0
-		for {
-			listRes, err := d.GetSimpleUsers(SimpleUserReq{
-				CommonDepartmentReq: CommonDepartmentReq{DeptID: dept},
-				Cursor:              cursor,
-				Size:                100,
-				OrderField:          EntryAsc,
-				ContainAccessLimit:  false,
-				Language:            ChineseLanguage,
-			})
+	header := http.Header{"x-acs-dingtalk-access-token": []string{accToken}}
+	var data UserSearchResp
+	err = d.post(d.apiBaseURL+userSearchAPI, &UserSearchReq{QueryWord: keyword, Offset: offset, Size: size}, &data, header)
+	if err != nil {
+		return nil, false, fmt.Errorf("搜索员工(%s)失败: %w", keyword, err)
+	}
 
-			if err != nil {
-				return nil, err
-			}
+	return data.List, data.HasMore, nil
+}
 
-			cursor = listRes.NextCursor
-			for _, u := range listRes.List {
-				users[u.UserID] = u
-			}
+// GetUserByEmail 根据邮箱(个人邮箱或企业邮箱，不区分大小写)查找员工，找不到时返回nil，见GetUserByEmailWithContext
+func (d *DingTalkClient) GetUserByEmail(email string) (*DingDingUser, error) {
+	return d.GetUserByEmailWithContext(context.Background(), email)
+}
 
-			if !listRes.HasMore {
-				break
-			}
-		}
+// GetUserByEmailWithContext 根据邮箱(个人邮箱或企业邮箱，不区分大小写)查找员工，找不到时返回nil
+// 钉钉没有按邮箱查询员工的接口，搜索接口(SearchUsers)也只匹配姓名和拼音，因此这里会先递归获取全部部门，
+// 再按部门逐页获取员工详情直到找到匹配的员工。最坏情况下需要请求一次部门列表接口以及每个部门(员工数/100+1)次员工详情接口，
+// 大型企业中一次查询可能需要数百上千次请求并触发频率限制，可以通过ctx限制查询时间；频繁查询时应自行缓存邮箱与userid的对应关系
+func (d *DingTalkClient) GetUserByEmailWithContext(ctx context.Context, email string) (*DingDingUser, error) {
+	email = strings.TrimSpace(email)
+	if email == "" {
+		return nil, nil
 	}
 
-	data := make([]*SimpleUser, 0, len(users))
-	for _, item := range users {
-		data = append(data, item)
+	depts, err := d.GetDepartmentsByParentWithContext(ctx, RootDeptID)
+	if err != nil {
+		return nil, err
 	}
-	return data, nil
-}
 
-func (d *DingTalkClient) GetUsersByDeptIDList(depts []uint64) ([]*DingDingUser, error) {
-	users := make(map[string]*DingDingUser)
-	for _, dept := range depts {
-		cursor := 0
-		for {
-			listRes, err := d.GetUsers(SimpleUserReq{
-				CommonDepartmentReq: CommonDepartmentReq{DeptID: dept},
-				Cursor:              cursor,
-				Size:                100,
-				OrderField:          EntryAsc,
-				ContainAccessLimit:  false,
-				Language:            ChineseLanguage,
-			})
+	for _, dept := range append([]uint64{RootDeptID}, depts...) {
+		req := SimpleUserReq{
+			CommonDepartmentReq: CommonDepartmentReq{DeptID: dept},
+			Size:                maxPageSize,
+			OrderField:          EntryAsc,
+			Language:            ChineseLanguage,
+		}
 
+		for {
+			listRes, err := d.GetUsersWithContext(ctx, req)
 			if err != nil {
 				return nil, err
 			}
 
-			cursor = listRes.NextCursor
-			for _, u := range listRes.List {
-				users[u.UserID] = u
+			if listRes == nil {
+				break
+			}
+
+			for _, user := range listRes.List {
+				if strings.EqualFold(user.Email, email) || strings.EqualFold(user.OrgEmail, email) {
+					return user, nil
+				}
 			}
 
 			if !listRes.HasMore {
 				break
 			}
+			req.Cursor = listRes.NextCursor
 		}
 	}
-
-	data := make([]*DingDingUser, 0, len(users))
-	for _, item := range users {
-		data = append(data, item)
-	}
-	return data, nil
+	return nil, nil
 }
 
-func (d *DingTalkClient) GetApprovalProcessIDList(params ApprovalProcessIDReq) (*ApprovalProcessRes, error) {
+// GetAdminList 获取企业的管理员列表
+func (d *DingTalkClient) GetAdminList() ([]*AdminInfo, error) {
 	accToken, err := d.GetAccessToken()
 	if err != nil {
 		return nil, err
 	}
 
-	reqUrl := fmt.Sprintf(domain+reqApprovalProcess, accToken)
-	var data ApprovalProcessIDListResp
-	err = post(reqUrl, &params, &data, nil)
-	if err != nil {
-		return nil, fmt.Errorf("请求审批流程(%s)失败: %v", params.ProcessCode, err)
+	reqUrl := fmt.Sprintf(d.oapiBaseURL+reqAdminList, accToken)
+	var data AdminListResp
+	if err = d.post(reqUrl, struct{}{}, &data, nil); err != nil {
+		return nil, fmt.Errorf("请求管理员列表失败: %w", err)
 	}
 
-	//fmt.Println(data)
 	if data.ErrCode != 0 {
-		return nil, fmt.Errorf("请求审批流程失败; %s(%d)", data.ErrMsg, data.ErrCode)
+		return nil, fmt.Errorf("请求管理员列表失败: %w", data.toError())
 	}
 
 	return data.Result, nil
 }
 
-func (d *DingTalkClient) GetApprovalDetail(processID string) (*ApprovalDetail, error) {
+// GetAdminScope 获取管理员可管理的部门ID列表
+func (d *DingTalkClient) GetAdminScope(userid string) ([]uint64, error) {
 	accToken, err := d.GetAccessToken()
 	if err != nil {
 		return nil, err
 	}
 
-	reqUrl := fmt.Sprintf(domain+reqApprovalDetail, accToken)
-	var data ApprovalDetailResp
-	err = post(reqUrl, &ApprovalDetailReq{ProcessInstanceID: processID}, &data, nil)
-	if err != nil {
-		return nil, fmt.Errorf("请求审批详情(%s)失败: %v", processID, err)
+	reqUrl := fmt.Sprintf(d.oapiBaseURL+reqAdminScope, accToken)
+	var data AdminScopeResp
+	if err = d.post(reqUrl, &UserGetReq{UserID: userid}, &data, nil); err != nil {
+		return nil, fmt.Errorf("请求管理员(%s)的管理范围失败: %w", userid, err)
 	}
 
 	if data.ErrCode != 0 {
-		return nil, fmt.Errorf("请求审批详情失败: %s(%d)", data.ErrMsg, data.ErrCode)
+		return nil, fmt.Errorf("请求管理员的管理范围失败: %w", data.toError())
 	}
 
-	return data.Detail, nil
+	return data.DeptIDs, nil
 }
 
-func (d *DingTalkClient) SendMessageFromRobot(robotCode, title, content string, to []string) (*SendMsgByRobotResp, error) {
+// GetRoleGroups 获取企业的全部角色组及其下的角色
+func (d *DingTalkClient) GetRoleGroups() ([]*RoleGroup, error) {
 	accToken, err := d.GetAccessToken()
 	if err != nil {
 		return nil, err
 	}
 
-	msg, err := json.Marshal(&MsgContent{Title: title, Text: content})
-	if err != nil {
-		return nil, fmt.Errorf("生成消息失败: %v", err)
-	}
+	reqUrl := fmt.Sprintf(d.oapiBaseURL+reqRoleList, accToken)
+	reqObj := &RoleListReq{Offset: 0, Size: 200}
+	var groups []*RoleGroup
+	for {
+		var data RoleListResp
+		if err = d.post(reqUrl, reqObj, &data, nil); err != nil {
+			return nil, fmt.Errorf("请求角色列表失败: %w", err)
+		}
 
-	if len(to) == 0 {
-		return nil, nil
-	}
+		if data.ErrCode != 0 {
+			return nil, fmt.Errorf("请求角色列表失败: %w", data.toError())
+		}
+
+		if data.Result == nil {
+			return groups, nil
+		}
 
-	if len(to) > 20 {
-		to = to[:20]
+		groups = append(groups, data.Result.List...)
+		if !data.Result.HasMore {
+			return groups, nil
+		}
+		reqObj.Offset += reqObj.Size
 	}
+}
 
-	backOff := NewBackoff()
-	reqObj := &SendMsgByRobotReq{
-		RobotCode: robotCode,
-		UserIDs:   to,
-		MsgKey:    "officialMarkdownMsg",
-		MsgParam:  string(msg),
+// GetUsersByRole 获取指定角色下的全部员工，自动翻页直到获取完毕
+// 员工在角色下的管理范围见RoleUser.ManageScopes，可通过GroupByDept按部门汇总userid
+func (d *DingTalkClient) GetUsersByRole(roleId int64) (RoleUserCollection, error) {
+	accToken, err := d.GetAccessToken()
+	if err != nil {
+		return nil, err
 	}
-	header := http.Header{"x-acs-dingtalk-access-token": []string{accToken}}
 
-	var ret SendMsgByRobotResp
-	retries := 0
+	reqUrl := fmt.Sprintf(d.oapiBaseURL+reqRoleUsers, accToken)
+	reqObj := &RoleUserReq{RoleID: roleId, Offset: 0, Size: maxPageSize}
+	var users RoleUserCollection
 	for {
-		if retries > 3 {
-			break
+		var data RoleUsersResp
+		if err = d.post(reqUrl, reqObj, &data, nil); err != nil {
+			return nil, fmt.Errorf("请求角色(%d)的员工列表失败: %w", roleId, err)
 		}
 
-		err = post(batchSendAPI, reqObj, &ret, header)
-		if err != nil {
-			d.log.Errorf("发送消息失败, 重试发送: %v", err)
-			time.Sleep(backOff.Duration(retries + 1))
-			retries += 1
-			continue
+		if data.ErrCode != 0 {
+			return nil, fmt.Errorf("请求角色员工列表失败: %w", data.toError())
+		}
+
+		if data.Result == nil {
+			return users, nil
 		}
 
-		break
+		users = append(users, data.Result.List...)
+		if !data.Result.HasMore {
+			return users, nil
+		}
+
+		if data.Result.NextCursor > reqObj.Offset {
+			reqObj.Offset = data.Result.NextCursor
+		} else {
+			reqObj.Offset += reqObj.Size
+		}
 	}
+}
 
+// GetExternalContacts 获取企业的全部外部联系人，自动翻页直到获取完毕
+func (d *DingTalkClient) GetExternalContacts() ([]*ExternalContact, error) {
+	accToken, err := d.GetAccessToken()
 	if err != nil {
-		return nil, fmt.Errorf("发送批量消息接口失败(Retries: %d): %v", retries, err)
+		return nil, err
 	}
 
-	return &ret, nil
+	reqUrl := fmt.Sprintf(d.oapiBaseURL+reqExtContactList, accToken)
+	reqObj := &ExtContactListReq{Offset: 0, Size: maxPageSize}
+	var contacts []*ExternalContact
+	for {
+		var data ExtContactListResp
+		if err = d.post(reqUrl, reqObj, &data, nil); err != nil {
+			return nil, fmt.Errorf("请求外部联系人列表失败: %w", err)
+		}
+
+		if data.ErrCode != 0 {
+			return nil, fmt.Errorf("请求外部联系人列表失败: %w", data.toError())
+		}
+
+		contacts = append(contacts, data.Results...)
+		// 接口没有返回是否还有更多数据，返回数量不足一页时即为最后一页
+		if len(data.Results) < reqObj.Size {
+			return contacts, nil
+		}
+		reqObj.Offset += reqObj.Size
+	}
 }
 
-func (d *DingTalkClient) GetProcessCode() error {
+// CreateCalendarEvent 在用户的主日历中创建日程
+// 新版日程接口使用unionId标识用户，userID和参与者的ID均需传入unionId
+func (d *DingTalkClient) CreateCalendarEvent(userID string, event CalendarEvent) (*CalendarEventResp, error) {
 	accToken, err := d.GetAccessToken()
 	if err != nil {
-		return err
+		return nil, err
 	}
-	reqUrl := fmt.Sprintf(domain+reqProcessCode, accToken)
 
-	var data ProcessCodeResult
-	err = post(reqUrl, &ProcessCodeReq{Name: "每日工作结果日志[V]"}, &data, nil)
-	if err != nil {
-		return fmt.Errorf("请求模版Code失败: %s(%d)", data.ErrMsg, data.ErrCode)
+	reqUrl := d.apiBaseURL + fmt.Sprintf(calendarEventsAPI, url.PathEscape(userID))
+	header := http.Header{"x-acs-dingtalk-access-token": []string{accToken}}
+	var data CalendarEventResp
+	if err = d.postContext(withAPILabel(context.Background(), calendarEventsAPI), reqUrl, &event, &data, header); err != nil {
+		return nil, fmt.Errorf("创建日程(%s)失败: %w", event.Summary, err)
 	}
 
-	fmt.Println(data.Code)
-	return nil
-}
-
-func (d *DingTalkClient) SendWorkNotify() {
-	// TODO
+	return &data, nil
 }
 
-func (d *DingTalkClient) GetUserIDFromScanQrCode(tmpCode string) (string, error) {
-	snsUserInfo, err := d.GetUserUnionIDByCode(tmpCode)
+// UploadMedia 上传媒体文件，返回的media_id可用于发送图片、文件等消息
+// mediaType为MediaImage、MediaVoice、MediaVideo、MediaFile之一
+func (d *DingTalkClient) UploadMedia(mediaType string, filename string, r io.Reader) (string, error) {
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+	part, err := writer.CreateFormFile("media", filename)
 	if err != nil {
-		return "", err
+		return "", fmt.Errorf("生成上传文件(%s)失败: %w", filename, err)
 	}
 
-	if snsUserInfo == nil {
-		return "", fmt.Errorf("无效的UnionID")
+	if _, err = io.Copy(part, r); err != nil {
+		return "", fmt.Errorf("读取上传文件(%s)失败: %w", filename, err)
+	}
+
+	if err = writer.Close(); err != nil {
+		return "", fmt.Errorf("生成上传文件(%s)失败: %w", filename, err)
 	}
 
-	userId, err := d.GetUserIDByUnionID(snsUserInfo.UnionID)
+	accToken, err := d.GetAccessToken()
 	if err != nil {
 		return "", err
 	}
 
-	return userId, nil
+	reqUrl := fmt.Sprintf(d.oapiBaseURL+reqMediaUpload, accToken, url.QueryEscape(mediaType))
+	header := http.Header{"Content-Type": []string{writer.FormDataContentType()}}
+	var data MediaUploadResp
+	if err = d.sendContext(context.Background(), reqUrl, buf.Bytes(), &data, header); err != nil {
+		return "", fmt.Errorf("上传媒体文件(%s)失败: %w", filename, err)
+	}
+
+	if data.ErrCode != 0 {
+		return "", fmt.Errorf("上传媒体文件失败: %w", data.toError())
+	}
+
+	return data.MediaID, nil
 }
 
-func (d *DingTalkClient) GetUserUnionIDByCode(tmpCode string) (*SnsUserInfo, error) {
+// resolveAgentID 解析调用时指定的agentId，为空时使用创建客户端时指定的agentId
+func (d *DingTalkClient) resolveAgentID(agentId string) (int64, error) {
+	if agentId == "" {
+		agentId = d.agentId
+	}
+	return parseAgentID(agentId)
+}
 
-	// 根据钉钉OpenAPI设定，通过钉钉扫码登陆过后拿到的临时登陆码换取用户信息步骤如下：
-	// 参考：https://open.dingtalk.com/document/orgapp-server/obtain-the-user-information-based-on-the-sns-temporary-authorization
-	// 1. 准备三个参数：accessKey (为应用的AppKey，在开发者后台应用详情页查看。)
-	// 2. timestamp （当前时间戳，单位毫秒。）
-	// 3. 对timestamp做签名后的结果（该结果为HashMacSha256->Base64编码->urlencode编码）
-	timestamp := strconv.FormatInt(time.Now().UnixNano()/1000000, 10)
-	hashFn := hmac.New(sha256.New, []byte(d.appSecret))
-	hashFn.Write([]byte(timestamp))
-	digest := hashFn.Sum(nil)
-	sig := url.QueryEscape(base64.StdEncoding.EncodeToString(digest))
-
-	reqUrl := fmt.Sprintf(domain+snsReq, d.appKey, timestamp, sig)
-	fmt.Println(reqUrl)
-	var data SnsResponse
-	err := post(reqUrl, &SnsRequest{TmpAuthCode: tmpCode}, &data, nil)
+func parseAgentID(agentId string) (int64, error) {
+	agentID, err := strconv.ParseInt(agentId, 10, 64)
 	if err != nil {
-		return nil, fmt.Errorf("根据sns临时授权码获取用户信息失败: %v", err)
+		return 0, fmt.Errorf("无效的AgentID(%s): %w", agentId, err)
 	}
+	return agentID, nil
+}
 
-	if data.ErrCode > 0 {
-		fmt.Println(data)
-		return nil, fmt.Errorf("%s(%d)", data.ErrMsg, data.ErrCode)
+// get 发送GET请求并解析结果
+func (d *DingTalkClient) get(reqUrl string, out interface{}) error {
+	if d.ctx.Err() != nil {
+		return ErrClientClosed
 	}
 
-	fmt.Println(data.UserInfo)
-	return data.UserInfo, nil
-}
-
-// GetUserIDByUnionID 根据unionid获取用户userid
-func (d *DingTalkClient) GetUserIDByUnionID(unionID string) (userId string, err error) {
-	accToken, err := d.GetAccessToken()
+	req, err := http.NewRequestWithContext(d.ctx, http.MethodGet, reqUrl, nil)
 	if err != nil {
-		return "", err
+		return fmt.Errorf("创建HTTP请求失败: %w", err)
 	}
 
-	reqUrl := fmt.Sprintf(domain+reqUserByUnionID, accToken)
-	var data UserIDResponse
-	if err = post(reqUrl, &UserIDReq{UnionID: unionID}, &data, nil); err != nil {
-		return "", err
+	resp, payload, err := d.do(req)
+	if err != nil {
+		return err
 	}
 
-	if data.ErrCode > 0 {
-		fmt.Println(data)
-		return "", fmt.Errorf("%s(%d)", data.ErrMsg, data.ErrCode)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return &HTTPError{StatusCode: resp.StatusCode, Status: resp.Status, Body: bodySnippet(payload)}
 	}
+	return decodeResult(payload, out)
+}
 
-	return data.Result.UserID, nil
+func (d *DingTalkClient) post(reqUrl string, data interface{}, out interface{}, header http.Header) error {
+	return d.postContext(context.Background(), reqUrl, data, out, header)
 }
 
-func post(reqUrl string, data interface{}, out interface{}, header http.Header) error {
+// postContext 发送POST请求并解析结果，触发钉钉接口频率限制时按照退避策略等待后重试
+func (d *DingTalkClient) postContext(ctx context.Context, reqUrl string, data interface{}, out interface{}, header http.Header) error {
 	param, _ := json.Marshal(data)
 	//fmt.Println(string(param))
-	reqParams := strings.NewReader(string(param))
+	return d.sendContext(ctx, reqUrl, param, out, header)
+}
 
-	req, err := http.NewRequest(http.MethodPost, reqUrl, reqParams)
-	if err != nil {
-		return fmt.Errorf("创建HTTP请求失败: %v", err)
+// sendContext 发送请求体已编码好的POST请求，header中未指定Content-Type时默认为JSON
+// 请求失败且错误可以重试时(默认为触发频率限制)，按照退避策略等待后重试，最多重试d.maxRetries次
+func (d *DingTalkClient) sendContext(ctx context.Context, reqUrl string, param []byte, out interface{}, header http.Header) error {
+	if d.ctx.Err() != nil {
+		return ErrClientClosed
 	}
 
-	req.Header.Set("Content-Type", "application/json; charset=utf-8")
-	for key, val := range header {
-		for _, item := range val {
-			req.Header.Add(key, item)
+	ctx, cancel := d.requestContext(ctx)
+	defer cancel()
+
+	backOff := d.newBackoff()
+	for retries := 0; ; retries++ {
+		resp, payload, err := d.doPost(ctx, reqUrl, param, header)
+		if attemptErr := responseError(resp, payload, err); attemptErr != nil && retries < d.maxRetries && d.retryable(attemptErr) {
+			var wait time.Duration
+			if resp != nil {
+				wait = retryAfter(resp.Header)
+			}
+
+			if err = d.waitRateLimit(ctx, backOff, retries, wait); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err != nil {
+			return err
 		}
+
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			return &HTTPError{StatusCode: resp.StatusCode, Status: resp.Status, Body: bodySnippet(payload)}
+		}
+
+		return decodeResult(payload, out)
+	}
+}
+
+// sendOnce 发送一次POST请求并解析结果，不进行任何重试，用于重复发送会产生副作用的接口
+// 返回的错误包括请求失败、HTTPError以及errcode不为0时的DingTalkError；wait为响应头中Retry-After指定的等待时间
+func (d *DingTalkClient) sendOnce(ctx context.Context, reqUrl string, data interface{}, out interface{}, header http.Header) (wait time.Duration, err error) {
+	if d.ctx.Err() != nil {
+		return 0, ErrClientClosed
 	}
-	resp, err := http.DefaultClient.Do(req)
+
+	param, err := json.Marshal(data)
 	if err != nil {
-		return fmt.Errorf("请求失败: %v", err)
+		return 0, fmt.Errorf("编码请求参数失败: %w", err)
 	}
 
-	body := resp.Body
-	defer func() { _ = body.Close() }()
-	if err = readResult(body, out); err != nil {
-		return err
+	ctx, cancel := d.requestContext(ctx)
+	defer cancel()
+
+	resp, payload, err := d.doPost(ctx, reqUrl, param, header)
+	if resp != nil {
+		wait = retryAfter(resp.Header)
 	}
 
-	return nil
+	if err = responseError(resp, payload, err); err != nil {
+		return wait, err
+	}
+	return wait, decodeResult(payload, out)
 }
 
-func readResult(body io.Reader, out interface{}) error {
-	payload, err := io.ReadAll(body)
+func (d *DingTalkClient) doPost(ctx context.Context, reqUrl string, param []byte, header http.Header) (*http.Response, []byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, reqUrl, bytes.NewReader(param))
 	if err != nil {
-		return fmt.Errorf("读取失败: %v", err)
+		return nil, nil, fmt.Errorf("创建HTTP请求失败: %w", err)
+	}
+
+	if header.Get("Content-Type") == "" {
+		req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	}
+	for key, val := range header {
+		for _, item := range val {
+			req.Header.Add(key, item)
+		}
 	}
+	return d.do(req)
+}
 
+func decodeResult(payload []byte, out interface{}) error {
 	//fmt.Println()
 	//fmt.Printf("%s\n", payload)
-	if out != nil {
-		if err = json.Unmarshal(payload, out); err != nil {
-			return fmt.Errorf("解析失败: %v", err)
+	if out == nil {
+		return nil
+	}
+
+	err := json.Unmarshal(payload, out)
+	// 字段的数字与字符串类型不一致时，修正后重新解析
+	fixed := payload
+	for i := 0; err != nil && i < maxDecodeFixes; i++ {
+		var ok bool
+		if fixed, ok = fixNumericField(fixed, err); !ok {
+			break
 		}
+		err = json.Unmarshal(fixed, out)
+	}
+
+	if err != nil {
+		return fmt.Errorf("解析失败: %w, 响应内容: %s", err, bodySnippet(payload))
 	}
 	return nil
 }
+
+// bodySnippet 截取响应内容的前maxSnippetSize个字节用于错误信息，避免错误信息过长
+func bodySnippet(payload []byte) string {
+	if len(payload) <= maxSnippetSize {
+		return string(payload)
+	}
+
+	snippet := payload[:maxSnippetSize]
+	// 去掉末尾被截断的多字节字符
+	for i := 0; i < utf8.UTFMax && len(snippet) > 0; i++ {
+		if r, size := utf8.DecodeLastRune(snippet); r != utf8.RuneError || size != 1 {
+			break
+		}
+		snippet = snippet[:len(snippet)-1]
+	}
+	return string(snippet) + "...(已截断)"
+}
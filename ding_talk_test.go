@@ -0,0 +1,325 @@
+package sdk
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testRetryDelay 测试中使用的重试退避时间，避免测试等待过久
+const testRetryDelay = time.Millisecond
+
+// newTestClient 创建指向httptest.Server的客户端，mux中已注册/gettoken
+func newTestClient(t *testing.T, mux *http.ServeMux, opts ...Option) *DingTalkClient {
+	t.Helper()
+
+	mux.HandleFunc("/gettoken", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"errcode":0,"errmsg":"ok","access_token":"test-token","expires_in":7200}`))
+	})
+	return newTestServerClient(t, mux, opts...)
+}
+
+// newTestServerClient 与newTestClient相同，由调用方自行在mux中注册/gettoken
+func newTestServerClient(t *testing.T, mux *http.ServeMux, opts ...Option) *DingTalkClient {
+	t.Helper()
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	opts = append([]Option{
+		WithOApiBaseURL(server.URL),
+		WithNewApiBaseURL(server.URL),
+		WithHTTPClient(server.Client()),
+		WithRetryBackoff(testRetryDelay, testRetryDelay),
+	}, opts...)
+	client := NewDingTalkClient("1", "key", "secret", opts...)
+	t.Cleanup(func() { _ = client.Close() })
+	return client
+}
+
+func TestGetSimpleUserByDeptIDListWithPageCursors(t *testing.T) {
+	const usersPerDept = 5
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/topapi/user/listsimple", func(w http.ResponseWriter, r *http.Request) {
+		var req SimpleUserReq
+		_ = json.NewDecoder(r.Body).Decode(&req)
+
+		page := &ListSimpleUserRes{}
+		for i := req.Cursor; i < usersPerDept && i < req.Cursor+req.Size; i++ {
+			page.List = append(page.List, &SimpleUser{UserID: fmt.Sprintf("%d-%d", req.DeptID, i)})
+		}
+		if next := req.Cursor + req.Size; next < usersPerDept {
+			page.HasMore, page.NextCursor = true, next
+		}
+		_ = json.NewEncoder(w).Encode(SimpleUserResp{Result: page})
+	})
+	client := newTestClient(t, mux)
+
+	users, cursors, err := client.GetSimpleUserByDeptIDListWithPage([]uint64{10, 20}, ChineseLanguage, PageParams{
+		Size:    2,
+		Cursors: map[uint64]int{20: 4},
+	})
+	if err != nil {
+		t.Fatalf("GetSimpleUserByDeptIDListWithPage: %v", err)
+	}
+
+	ids := make([]string, 0, len(users))
+	for _, u := range users {
+		ids = append(ids, u.UserID)
+	}
+	sort.Strings(ids)
+	want := []string{"10-0", "10-1", "10-2", "10-3", "10-4", "20-4"}
+	if fmt.Sprint(ids) != fmt.Sprint(want) {
+		t.Errorf("users = %v, want %v", ids, want)
+	}
+
+	// 返回各部门最后请求的游标
+	if cursors[10] != 4 || cursors[20] != 4 {
+		t.Errorf("cursors = %v, want map[10:4 20:4]", cursors)
+	}
+}
+
+func TestPageParamsValidate(t *testing.T) {
+	cases := []struct {
+		page PageParams
+		ok   bool
+	}{
+		{PageParams{Size: 1}, true},
+		{PageParams{Size: maxPageSize, Cursors: map[uint64]int{1: 10}}, true},
+		{PageParams{Size: 0}, false},
+		{PageParams{Size: maxPageSize + 1}, false},
+		{PageParams{Size: 10, Cursors: map[uint64]int{1: -1}}, false},
+	}
+
+	for _, c := range cases {
+		if err := c.page.validate(); (err == nil) != c.ok {
+			t.Errorf("validate(%+v) = %v, want ok=%v", c.page, err, c.ok)
+		}
+	}
+}
+
+func TestSendRobotMessageAttempts(t *testing.T) {
+	cases := []struct {
+		name   string
+		status int
+		opts   []Option
+		want   int32
+	}{
+		{"rate limited", http.StatusTooManyRequests, nil, robotMaxAttempts},
+		{"rate limited without retry", http.StatusTooManyRequests, []Option{WithMaxRetries(0)}, 1},
+		{"rate limited with one retry", http.StatusTooManyRequests, []Option{WithMaxRetries(1)}, 2},
+		{"rate limited with more retries", http.StatusTooManyRequests, []Option{WithMaxRetries(10)}, robotMaxAttempts},
+		{"bad gateway", http.StatusBadGateway, nil, 1},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var calls int32
+			mux := http.NewServeMux()
+			mux.HandleFunc(batchSendAPI, func(w http.ResponseWriter, r *http.Request) {
+				atomic.AddInt32(&calls, 1)
+				w.WriteHeader(c.status)
+			})
+			client := newTestClient(t, mux, c.opts...)
+
+			_, err := client.SendRobotMessage("robot", &TextMessage{Content: "hello"}, []string{"u1"})
+			var httpErr *HTTPError
+			if !errors.As(err, &httpErr) || httpErr.StatusCode != c.status {
+				t.Fatalf("err = %v, want HTTPError(%d)", err, c.status)
+			}
+
+			if calls != c.want {
+				t.Errorf("calls = %d, want %d", calls, c.want)
+			}
+			if attempts := fmt.Sprintf("Attempts: %d", c.want); !strings.Contains(err.Error(), attempts) {
+				t.Errorf("err = %v, want %q", err, attempts)
+			}
+		})
+	}
+}
+
+func TestSendRobotMessageBatches(t *testing.T) {
+	var batches [][]string
+	mux := http.NewServeMux()
+	mux.HandleFunc(batchSendAPI, func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("x-acs-dingtalk-access-token") != "test-token" {
+			t.Errorf("缺少access_token")
+		}
+
+		var req SendMsgByRobotReq
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		batches = append(batches, req.UserIDs)
+		_ = json.NewEncoder(w).Encode(SendMsgByRobotResp{InvalidStaffIdList: req.UserIDs[:1]})
+	})
+	client := newTestClient(t, mux)
+
+	to := make([]string, robotBatchSize*2+5)
+	for i := range to {
+		to[i] = fmt.Sprintf("u%d", i)
+	}
+
+	resp, err := client.SendRobotMessage("robot", &TextMessage{Content: "hello"}, to)
+	if err != nil {
+		t.Fatalf("SendRobotMessage: %v", err)
+	}
+
+	if len(batches) != 3 || len(batches[0]) != robotBatchSize || len(batches[2]) != 5 {
+		t.Errorf("batches = %v", batches)
+	}
+	if len(resp.InvalidStaffIdList) != 3 {
+		t.Errorf("InvalidStaffIdList = %v, want 3 ids", resp.InvalidStaffIdList)
+	}
+}
+
+func TestAccessTokenPermissionHint(t *testing.T) {
+	const hint = "请检查访问API权限"
+
+	cases := map[int]bool{
+		40089:              true,
+		ErrCodeRateLimited: false,
+	}
+
+	for errcode, wantHint := range cases {
+		mux := http.NewServeMux()
+		mux.HandleFunc("/gettoken", func(w http.ResponseWriter, r *http.Request) {
+			_, _ = fmt.Fprintf(w, `{"errcode":%d,"errmsg":"error"}`, errcode)
+		})
+		client := newTestServerClient(t, mux)
+
+		_, err := client.GetAccessToken()
+		var dtErr *DingTalkError
+		if !errors.As(err, &dtErr) || dtErr.ErrCode != errcode {
+			t.Fatalf("err = %v, want DingTalkError(%d)", err, errcode)
+		}
+
+		if got := strings.Contains(err.Error(), hint); got != wantHint {
+			t.Errorf("errcode %d: err = %v, want hint=%v", errcode, err, wantHint)
+		}
+	}
+}
+
+func TestAccessTokenRefreshWithoutHoldingCache(t *testing.T) {
+	const delay = 300 * time.Millisecond
+
+	var calls int32
+	requested := make(chan struct{}, 1)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/gettoken", func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			requested <- struct{}{}
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		_, _ = w.Write([]byte(`{"errcode":0,"errmsg":"ok","access_token":"test-token","expires_in":7200}`))
+	})
+	client := newTestServerClient(t, mux, WithRetryBackoff(delay, delay))
+
+	var wg sync.WaitGroup
+	tokens := make([]string, 4)
+	for i := range tokens {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			tokens[i], _ = client.GetAccessToken()
+		}(i)
+	}
+
+	// 第一次请求触发频率限制后，刷新在退避等待期间不应阻塞读取缓存
+	<-requested
+	start := time.Now()
+	client.TokenExpiry()
+	if elapsed := time.Since(start); elapsed > delay/2 {
+		t.Errorf("TokenExpiry在刷新access_token期间被阻塞了%v", elapsed)
+	}
+
+	wg.Wait()
+	for i, token := range tokens {
+		if token != "test-token" {
+			t.Errorf("tokens[%d] = %q", i, token)
+		}
+	}
+
+	// 并发的刷新只请求一次access_token(加上一次重试)
+	if calls != 2 {
+		t.Errorf("calls = %d, want 2", calls)
+	}
+}
+
+// emailDirectoryMux 部门1下有子部门2，部门2下有两页员工
+func emailDirectoryMux() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/topapi/v2/department/listsubid", func(w http.ResponseWriter, r *http.Request) {
+		var req DepartmentChildrenReq
+		_ = json.NewDecoder(r.Body).Decode(&req)
+
+		resp := DepartmentChildrenResp{Result: &DeptIDList{}}
+		if req.DeptID == RootDeptID {
+			resp.Result.DeptIDList = []uint64{2}
+		}
+		_ = json.NewEncoder(w).Encode(resp)
+	})
+	mux.HandleFunc("/topapi/v2/user/list", func(w http.ResponseWriter, r *http.Request) {
+		var req SimpleUserReq
+		_ = json.NewDecoder(r.Body).Decode(&req)
+
+		page := &ListUserDetailRes{}
+		switch {
+		case req.DeptID == 2 && req.Cursor == 0:
+			page.List = []*DingDingUser{{UserID: "u1", Email: "a@example.com"}}
+			page.HasMore, page.NextCursor = true, 1
+		case req.DeptID == 2 && req.Cursor == 1:
+			page.List = []*DingDingUser{{UserID: "u2", OrgEmail: "B@Corp.example.com"}}
+		}
+		_ = json.NewEncoder(w).Encode(UserDetailResp{Result: page})
+	})
+	return mux
+}
+
+func TestGetUserByEmail(t *testing.T) {
+	client := newTestClient(t, emailDirectoryMux())
+
+	cases := map[string]string{
+		"a@example.com":        "u1",
+		" b@corp.example.com ": "u2",
+		"missing@example.com":  "",
+		"":                     "",
+	}
+	for email, want := range cases {
+		user, err := client.GetUserByEmail(email)
+		if err != nil {
+			t.Fatalf("GetUserByEmail(%q): %v", email, err)
+		}
+
+		got := ""
+		if user != nil {
+			got = user.UserID
+		}
+		if got != want {
+			t.Errorf("GetUserByEmail(%q) = %q, want %q", email, got, want)
+		}
+	}
+}
+
+func TestGetUserByEmailWithContextCanceled(t *testing.T) {
+	client := newTestClient(t, emailDirectoryMux())
+	if _, err := client.GetAccessToken(); err != nil {
+		t.Fatalf("GetAccessToken: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := client.GetUserByEmailWithContext(ctx, "b@corp.example.com"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+}
@@ -0,0 +1,77 @@
+package sdk
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrNoRecipients    = errors.New("未指定消息接收人")          // 发送消息时接收人列表为空
+	ErrEmptyRobotCode  = errors.New("机器人的robotCode不能为空") // 通过机器人发送消息时未指定robotCode
+	ErrProcessNotFound = errors.New("审批模板不存在")           // 根据名称找不到对应的审批模板
+	ErrClientClosed    = errors.New("客户端已关闭")            // 调用Close后继续调用钉钉接口
+)
+
+// 钉钉接口常见的错误码(errcode)
+const (
+	ErrCodeSystemBusy      = -1    // 系统繁忙
+	ErrCodeOK              = 0     // 请求成功
+	ErrCodeRateLimited     = 88    // 请求频率超限，具体原因见errmsg(sub_code)
+	ErrCodeInvalidToken    = 40014 // 不合法的access_token
+	ErrCodeTokenExpired    = 42001 // access_token已过期
+	ErrCodeDeptNotFound    = 60003 // 部门不存在
+	ErrCodeNoPermission    = 60011 // 没有调用该接口的权限
+	ErrCodeUserNotFound    = 60121 // 找不到该用户
+	ErrCodeAppRateLimited  = 90002 // 当前应用调用接口的频率超限
+	ErrCodeQPSLimited      = 90006 // 接口调用超过QPS限制
+	ErrCodeCorpRateLimited = 90018 // 当前企业调用接口的频率超限
+)
+
+// IsRetryable 判断错误码对应的错误是否可以通过稍后重试解决，如系统繁忙、频率限制等
+func IsRetryable(errcode int) bool {
+	switch errcode {
+	case ErrCodeSystemBusy, ErrCodeRateLimited, ErrCodeAppRateLimited, ErrCodeCorpRateLimited, ErrCodeQPSLimited:
+		return true
+	}
+	return false
+}
+
+// IsTokenInvalid 判断错误码是否表示access_token无效或已过期，此时需要重新获取access_token
+func IsTokenInvalid(errcode int) bool {
+	return errcode == ErrCodeInvalidToken || errcode == ErrCodeTokenExpired
+}
+
+// DingTalkError 钉钉接口返回的业务错误(errcode不为0)，可以通过errors.As获取
+type DingTalkError struct {
+	ErrCode   int
+	ErrMsg    string
+	RequestID string // 钉钉返回的request_id，向钉钉提交工单时需要提供
+}
+
+func (e *DingTalkError) Error() string {
+	if e.RequestID == "" {
+		return fmt.Sprintf("%s(%d)", e.ErrMsg, e.ErrCode)
+	}
+	return fmt.Sprintf("%s(%d), request_id: %s", e.ErrMsg, e.ErrCode, e.RequestID)
+}
+
+// IsRetryable 是否可以稍后重试
+func (e *DingTalkError) IsRetryable() bool {
+	return IsRetryable(e.ErrCode)
+}
+
+// IsTokenInvalid access_token是否无效或已过期
+func (e *DingTalkError) IsTokenInvalid() bool {
+	return IsTokenInvalid(e.ErrCode)
+}
+
+// HTTPError 钉钉接口返回了非2xx的HTTP状态码，Body为截断后的响应内容
+type HTTPError struct {
+	StatusCode int
+	Status     string
+	Body       string
+}
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("HTTP请求失败: %s, 响应内容: %s", e.Status, e.Body)
+}
@@ -0,0 +1,96 @@
+package sdk
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// Hooks 调用钉钉接口时的观测回调，可用于统计各接口的调用次数、耗时及错误率
+// api为接口路径(不含查询参数)，如"/topapi/v2/user/get"；路径中包含userid等参数的接口为构造请求的模板，
+// 如"/v1.0/calendar/users/%s/calendars/primary/events"，避免监控指标的标签随参数无限增长。触发频率限制重试时每次请求都会回调。
+// 回调在发起请求的goroutine中同步执行，实现时应避免阻塞；并发调用接口时回调也会被并发执行，实现需要是并发安全的。
+type Hooks interface {
+	OnRequest(api string)
+	OnResponse(api string, dur time.Duration, err error)
+}
+
+// do 发送HTTP请求并读取响应内容，请求前后调用Hooks；dry-run模式下不发送请求
+func (d *DingTalkClient) do(req *http.Request) (*http.Response, []byte, error) {
+	if d.userAgent != "" && req.Header.Get("User-Agent") == "" {
+		req.Header.Set("User-Agent", d.userAgent)
+	}
+	if d.acceptLanguage != "" && req.Header.Get("Accept-Language") == "" {
+		req.Header.Set("Accept-Language", d.acceptLanguage)
+	}
+
+	if resp, payload, handled := d.inspect(req); handled {
+		return resp, payload, nil
+	}
+
+	api := apiLabel(req)
+	if d.hooks != nil {
+		d.hooks.OnRequest(api)
+	}
+
+	start := time.Now()
+	resp, payload, err := d.roundTrip(req)
+	if d.hooks != nil {
+		d.hooks.OnResponse(api, time.Since(start), responseError(resp, payload, err))
+	}
+	return resp, payload, err
+}
+
+func (d *DingTalkClient) roundTrip(req *http.Request) (*http.Response, []byte, error) {
+	resp, err := d.httpClient.Do(req)
+	if err != nil {
+		// 请求因客户端关闭而被取消
+		if d.ctx.Err() != nil {
+			return nil, nil, ErrClientClosed
+		}
+		return nil, nil, fmt.Errorf("请求失败: %w", err)
+	}
+
+	body := resp.Body
+	defer func() { _ = body.Close() }()
+	payload, err := io.ReadAll(body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("读取失败: %w", err)
+	}
+
+	return resp, payload, nil
+}
+
+// responseError 汇总请求错误、HTTP状态码及钉钉的errcode，作为Hooks观测到的调用结果
+func responseError(resp *http.Response, payload []byte, err error) error {
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return &HTTPError{StatusCode: resp.StatusCode, Status: resp.Status, Body: bodySnippet(payload)}
+	}
+
+	var probe CommonResp
+	if decodeResult(payload, &probe) == nil && probe.ErrCode != 0 {
+		return probe.toError()
+	}
+	return nil
+}
+
+type apiLabelKey struct{}
+
+// withAPILabel 为路径中包含参数的接口指定传给Hooks的api，template为构造请求路径的模板
+func withAPILabel(ctx context.Context, template string) context.Context {
+	return context.WithValue(ctx, apiLabelKey{}, template)
+}
+
+// apiLabel 返回传给Hooks的api，未通过withAPILabel指定时为请求路径
+func apiLabel(req *http.Request) string {
+	if template, ok := req.Context().Value(apiLabelKey{}).(string); ok {
+		return template
+	}
+	return req.URL.Path
+}
@@ -0,0 +1,61 @@
+package sdk
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+type recordHooks struct {
+	mutex sync.Mutex
+	apis  []string
+}
+
+func (h *recordHooks) OnRequest(api string) {
+	h.mutex.Lock()
+	h.apis = append(h.apis, api)
+	h.mutex.Unlock()
+}
+
+func (h *recordHooks) OnResponse(string, time.Duration, error) {}
+
+func TestHooksAPILabel(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/gettoken", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"errcode":0,"errmsg":"ok","access_token":"test-token","expires_in":7200}`))
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"errcode":0,"errmsg":"ok"}`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	hooks := new(recordHooks)
+	client := NewDingTalkClient("1", "key", "secret",
+		WithOApiBaseURL(server.URL),
+		WithNewApiBaseURL(server.URL),
+		WithHTTPClient(server.Client()),
+		WithHooks(hooks),
+	)
+
+	for _, union := range []string{"union-a", "union-b"} {
+		if _, err := client.CreateCalendarEvent(union, CalendarEvent{Summary: "s"}); err != nil {
+			t.Fatalf("CreateCalendarEvent: %v", err)
+		}
+	}
+	if _, err := client.GetUserDetail("u1", ChineseLanguage); err != nil {
+		t.Fatalf("GetUserDetail: %v", err)
+	}
+
+	want := []string{"/gettoken", calendarEventsAPI, calendarEventsAPI, "/topapi/v2/user/get"}
+	if len(hooks.apis) != len(want) {
+		t.Fatalf("apis = %v, want %v", hooks.apis, want)
+	}
+	for i := range want {
+		if hooks.apis[i] != want[i] {
+			t.Errorf("apis[%d] = %q, want %q", i, hooks.apis[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package sdk
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+// dryRunAccessToken dry-run模式下使用的access_token，不会请求钉钉获取真实的access_token
+const dryRunAccessToken = "DRY_RUN_ACCESS_TOKEN"
+
+// dryRunPayload dry-run模式下代替钉钉响应的内容，各接口按成功处理并返回空结果
+var dryRunPayload = []byte(`{"errcode":0,"errmsg":"dry run"}`)
+
+// redactedValue 交给Inspector的URL中敏感参数被替换成的值
+const redactedValue = "REDACTED"
+
+// InspectedRequest 即将发送给钉钉的请求，URL中的appsecret及请求体中的clientSecret已被替换为REDACTED
+type InspectedRequest struct {
+	Method string
+	URL    string
+	Header http.Header
+	Body   []byte
+}
+
+// Inspector 在请求发送前调用，用于检查SDK实际发送的请求内容
+type Inspector func(req *InspectedRequest)
+
+// inspect 将请求交给Inspector检查，dry-run模式下不发送请求并返回成功的响应
+// 返回的handled为true时表示请求已被处理，不需要再发送
+func (d *DingTalkClient) inspect(req *http.Request) (resp *http.Response, payload []byte, handled bool) {
+	if d.inspector != nil {
+		var body []byte
+		if req.GetBody != nil {
+			if rc, err := req.GetBody(); err == nil {
+				body, _ = io.ReadAll(rc)
+				_ = rc.Close()
+			}
+		}
+
+		d.inspector(&InspectedRequest{
+			Method: req.Method,
+			URL:    redactURL(req.URL),
+			Header: req.Header.Clone(),
+			Body:   redactBody(body),
+		})
+	}
+
+	if !d.dryRun {
+		return nil, nil, false
+	}
+
+	resp = &http.Response{
+		Status:     "200 OK",
+		StatusCode: http.StatusOK,
+		Header:     make(http.Header),
+		Body:       io.NopCloser(bytes.NewReader(dryRunPayload)),
+		Request:    req,
+	}
+	return resp, dryRunPayload, true
+}
+
+// redactURL 将获取access_token请求中的appsecret替换为redactedValue，避免应用密钥出现在检查结果中
+func redactURL(u *url.URL) string {
+	query := u.Query()
+	if query.Get("appsecret") == "" {
+		return u.String()
+	}
+
+	redacted := *u
+	query.Set("appsecret", redactedValue)
+	redacted.RawQuery = query.Encode()
+	return redacted.String()
+}
+
+// redactBody 将获取用户access_token请求体中的clientSecret替换为redactedValue
+func redactBody(body []byte) []byte {
+	if !bytes.Contains(body, []byte(`"clientSecret"`)) {
+		return body
+	}
+
+	var data map[string]interface{}
+	if json.Unmarshal(body, &data) != nil {
+		return body
+	}
+
+	data["clientSecret"] = redactedValue
+	redacted, err := json.Marshal(data)
+	if err != nil {
+		return body
+	}
+	return redacted
+}
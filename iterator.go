@@ -0,0 +1,106 @@
+package sdk
+
+// UserIterator 按需翻页遍历部门下的员工基本信息，每次只缓存一页数据
+// UserIterator不是并发安全的，不能在多个goroutine中同时调用Next
+//
+//	it := client.IterateSimpleUsers(deptID)
+//	for user, ok := it.Next(); ok; user, ok = it.Next() {
+//		...
+//	}
+//	if err := it.Err(); err != nil {
+//		...
+//	}
+type UserIterator struct {
+	client  *DingTalkClient
+	req     SimpleUserReq
+	page    []*SimpleUser
+	hasMore bool
+	err     error
+}
+
+// IterateSimpleUsers 返回遍历部门下员工基本信息的迭代器
+func (d *DingTalkClient) IterateSimpleUsers(deptID uint64) *UserIterator {
+	return &UserIterator{
+		client: d,
+		req: SimpleUserReq{
+			CommonDepartmentReq: CommonDepartmentReq{DeptID: deptID},
+			Cursor:              0,
+			Size:                100,
+			OrderField:          EntryAsc,
+			ContainAccessLimit:  false,
+			Language:            ChineseLanguage,
+		},
+		hasMore: true,
+	}
+}
+
+// Next 返回下一个员工，遍历结束或出错时返回false，出错原因通过Err获取
+func (it *UserIterator) Next() (*SimpleUser, bool) {
+	for len(it.page) == 0 {
+		if it.err != nil || !it.hasMore {
+			return nil, false
+		}
+		it.fetch()
+	}
+
+	user := it.page[0]
+	it.page = it.page[1:]
+	return user, true
+}
+
+// Err 返回遍历过程中发生的错误
+func (it *UserIterator) Err() error {
+	return it.err
+}
+
+func (it *UserIterator) fetch() {
+	listRes, err := it.client.GetSimpleUsers(it.req)
+	if err != nil {
+		it.err = err
+		return
+	}
+
+	if listRes == nil {
+		it.hasMore = false
+		return
+	}
+
+	it.page = listRes.List
+	it.hasMore = listRes.HasMore
+	it.req.Cursor = listRes.NextCursor
+}
+
+// ForEachUser 按页获取部门下员工的详细信息，并对每个员工调用fn，每次只缓存一页数据
+// fn返回错误时停止遍历并返回该错误
+func (d *DingTalkClient) ForEachUser(deptID uint64, fn func(user *DingDingUser) error) error {
+	req := SimpleUserReq{
+		CommonDepartmentReq: CommonDepartmentReq{DeptID: deptID},
+		Cursor:              0,
+		Size:                maxPageSize,
+		OrderField:          EntryAsc,
+		ContainAccessLimit:  false,
+		Language:            ChineseLanguage,
+	}
+
+	for {
+		listRes, err := d.GetUsers(req)
+		if err != nil {
+			return err
+		}
+
+		if listRes == nil {
+			return nil
+		}
+
+		for _, user := range listRes.List {
+			if err = fn(user); err != nil {
+				return err
+			}
+		}
+
+		if !listRes.HasMore {
+			return nil
+		}
+		req.Cursor = listRes.NextCursor
+	}
+}
@@ -0,0 +1,10 @@
+package sdk
+
+// Logger SDK输出日志使用的接口，go-log、zap的SugaredLogger均已实现该接口，
+// 其它日志库(如zerolog)可以通过简单的适配接入
+type Logger interface {
+	Debugf(template string, args ...interface{})
+	Infof(template string, args ...interface{})
+	Warnf(template string, args ...interface{})
+	Errorf(template string, args ...interface{})
+}
@@ -0,0 +1,119 @@
+package sdk
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Message 机器人单聊消息，每种消息类型对应钉钉的一个msgKey，
+// 消息本身序列化后的JSON即为请求中的msgParam
+type Message interface {
+	MsgKey() string
+}
+
+// MsgKey 官方markdown消息
+func (m *MsgContent) MsgKey() string {
+	return "officialMarkdownMsg"
+}
+
+// TextMessage 文本消息
+type TextMessage struct {
+	Content string `json:"content"`
+}
+
+func (m *TextMessage) MsgKey() string {
+	return "sampleText"
+}
+
+// MarkdownMessage markdown消息
+type MarkdownMessage struct {
+	Title string `json:"title"`
+	Text  string `json:"text"`
+}
+
+func (m *MarkdownMessage) MsgKey() string {
+	return "sampleMarkdown"
+}
+
+// LinkMessage 链接消息
+type LinkMessage struct {
+	Title      string `json:"title"`
+	Text       string `json:"text"`
+	PicURL     string `json:"picUrl"`
+	MessageURL string `json:"messageUrl"`
+}
+
+func (m *LinkMessage) MsgKey() string {
+	return "sampleLink"
+}
+
+// ImageMessage 图片消息，MediaID为通过UploadMedia上传图片得到的media_id，也可以是图片的URL
+type ImageMessage struct {
+	MediaID string `json:"photoURL"`
+}
+
+func (m *ImageMessage) MsgKey() string {
+	return "sampleImageMsg"
+}
+
+// FileMessage 文件消息，MediaID为通过UploadMedia上传文件得到的media_id
+type FileMessage struct {
+	MediaID  string `json:"mediaId"`
+	FileName string `json:"fileName"`
+	FileType string `json:"fileType"` // 文件扩展名，如pdf、xlsx
+}
+
+func (m *FileMessage) MsgKey() string {
+	return "sampleFile"
+}
+
+// ActionCardButton 卡片消息的按钮
+type ActionCardButton struct {
+	Title string
+	URL   string
+}
+
+// ActionCardMessage 卡片消息
+// 未设置Buttons时为整体跳转的卡片(sampleActionCard)，使用SingleTitle和SingleURL；
+// 设置2~5个Buttons时为竖向排列的独立跳转按钮(sampleActionCard2 ~ sampleActionCard5)。
+type ActionCardMessage struct {
+	Title       string
+	Text        string
+	SingleTitle string
+	SingleURL   string
+	Buttons     []ActionCardButton
+}
+
+func (m *ActionCardMessage) MsgKey() string {
+	if len(m.Buttons) < 2 {
+		return "sampleActionCard"
+	}
+	return fmt.Sprintf("sampleActionCard%d", len(m.Buttons))
+}
+
+func (m *ActionCardMessage) MarshalJSON() ([]byte, error) {
+	if len(m.Buttons) > 5 {
+		return nil, fmt.Errorf("卡片消息最多支持5个按钮, 当前%d个", len(m.Buttons))
+	}
+
+	param := map[string]string{
+		"title": m.Title,
+		"text":  m.Text,
+	}
+
+	switch len(m.Buttons) {
+	case 0:
+		param["singleTitle"] = m.SingleTitle
+		param["singleURL"] = m.SingleURL
+	case 1:
+		param["singleTitle"] = m.Buttons[0].Title
+		param["singleURL"] = m.Buttons[0].URL
+	default:
+		for i, btn := range m.Buttons {
+			param[fmt.Sprintf("actionTitle%d", i+1)] = btn.Title
+			param[fmt.Sprintf("actionURL%d", i+1)] = btn.URL
+		}
+	}
+
+	return json.Marshal(param)
+}
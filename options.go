@@ -0,0 +1,186 @@
+package sdk
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Option 创建DingTalkClient时的可选配置
+type Option func(*DingTalkClient)
+
+// WithLogger 设置SDK使用的日志，默认使用go-log的"dingtalk"日志
+func WithLogger(logger Logger) Option {
+	return func(d *DingTalkClient) {
+		if logger != nil {
+			d.log = logger
+		}
+	}
+}
+
+// Endpoint 钉钉服务端API的服务地址，包括旧版API(oapi)和新版API(v1.0)两个域名
+type Endpoint struct {
+	OApiBaseURL string
+	ApiBaseURL  string
+}
+
+// EndpointChina 钉钉中国区公有云的服务地址，也是客户端的默认配置
+var EndpointChina = Endpoint{
+	OApiBaseURL: defaultOApiBaseURL,
+	ApiBaseURL:  defaultApiBaseURL,
+}
+
+// WithEndpoint 同时设置旧版和新版API的服务地址
+// 海外租户、政务钉钉及专属钉钉等部署使用独立的域名，使用钉钉提供的域名构造Endpoint即可
+func WithEndpoint(endpoint Endpoint) Option {
+	return func(d *DingTalkClient) {
+		WithOApiBaseURL(endpoint.OApiBaseURL)(d)
+		WithNewApiBaseURL(endpoint.ApiBaseURL)(d)
+	}
+}
+
+// WithOApiBaseURL 设置旧版服务端API(oapi.dingtalk.com)的服务地址，可用于对接mock服务或专有云
+func WithOApiBaseURL(baseURL string) Option {
+	return func(d *DingTalkClient) {
+		if baseURL != "" {
+			d.oapiBaseURL = strings.TrimRight(baseURL, "/")
+		}
+	}
+}
+
+// WithNewApiBaseURL 设置新版服务端API(api.dingtalk.com)的服务地址
+func WithNewApiBaseURL(baseURL string) Option {
+	return func(d *DingTalkClient) {
+		if baseURL != "" {
+			d.apiBaseURL = strings.TrimRight(baseURL, "/")
+		}
+	}
+}
+
+// WithConcurrency 设置批量查询(如按部门列表获取员工)时并发请求的数量
+func WithConcurrency(n int) Option {
+	return func(d *DingTalkClient) {
+		if n > 0 {
+			d.concurrency = n
+		}
+	}
+}
+
+// WithHTTPClient 设置调用钉钉接口使用的http.Client，默认为http.DefaultClient
+// 可用于设置超时、代理，或在测试中替换为指向httptest.Server的客户端
+func WithHTTPClient(client *http.Client) Option {
+	return func(d *DingTalkClient) {
+		if client != nil {
+			d.httpClient = client
+		}
+	}
+}
+
+// WithHooks 设置接口调用的观测回调，用于对接监控系统统计调用次数、耗时及错误率
+func WithHooks(hooks Hooks) Option {
+	return func(d *DingTalkClient) {
+		d.hooks = hooks
+	}
+}
+
+// WithAcceptLanguage 设置请求头中的Accept-Language，如"en_US"，部分接口会按该语言返回errmsg
+// 与接口参数中的language不同，该设置对所有请求生效
+func WithAcceptLanguage(lang Lang) Option {
+	return func(d *DingTalkClient) {
+		d.acceptLanguage = string(lang)
+	}
+}
+
+// WithMaxRetries 设置请求失败且可以重试时最多重试的次数，默认为3次，为0时不重试
+// 机器人批量发送消息时每个批次最多请求3次(包括第一次)，避免触发频率限制时长时间重复发送
+func WithMaxRetries(n int) Option {
+	return func(d *DingTalkClient) {
+		if n >= 0 {
+			d.maxRetries = n
+		}
+	}
+}
+
+// WithRetryableChecker 设置判断请求失败后是否可以重试的函数，默认只重试触发频率限制的请求
+// err可能是网络错误、*HTTPError或*DingTalkError，可以结合IsRetryable等函数判断
+func WithRetryableChecker(fn func(err error) bool) Option {
+	return func(d *DingTalkClient) {
+		if fn != nil {
+			d.retryable = fn
+		}
+	}
+}
+
+// WithRetryBackoff 设置重试退避的初始等待时间和最大等待时间，参数无效时使用默认值
+func WithRetryBackoff(baseDelay, maxDelay time.Duration) Option {
+	return func(d *DingTalkClient) {
+		d.retryBaseDelay = baseDelay
+		d.retryMaxDelay = maxDelay
+	}
+}
+
+// WithTokenSafetyMargin 设置access_token提前过期的安全余量，默认为5分钟，为0时使用钉钉返回的有效期
+func WithTokenSafetyMargin(margin time.Duration) Option {
+	return func(d *DingTalkClient) {
+		if margin >= 0 {
+			d.tokenSafetyMargin = margin
+		}
+	}
+}
+
+// WithUserAgent 设置请求头中的User-Agent，便于在钉钉和自身的链路追踪中识别请求来源，默认为"go-ding-sdk/1.0"
+func WithUserAgent(userAgent string) Option {
+	return func(d *DingTalkClient) {
+		if userAgent != "" {
+			d.userAgent = userAgent
+		}
+	}
+}
+
+// WithInspector 设置请求发送前的检查回调，可以查看每个请求的URL、请求头和请求体
+// 注意旧版接口的URL中包含access_token，记录日志时需要自行脱敏；获取access_token请求中的appsecret和clientSecret会被替换为REDACTED
+func WithInspector(inspector Inspector) Option {
+	return func(d *DingTalkClient) {
+		d.inspector = inspector
+	}
+}
+
+// WithDryRun 开启dry-run模式，各接口只构造请求并交给Inspector检查，不会发送给钉钉
+// 该模式下不会获取真实的access_token，所有接口均按成功处理并返回空结果，适用于在写操作上线前确认请求内容
+func WithDryRun(inspector Inspector) Option {
+	return func(d *DingTalkClient) {
+		d.dryRun = true
+		if inspector != nil {
+			d.inspector = inspector
+		}
+	}
+}
+
+// WithProxy 设置调用钉钉接口使用的HTTP代理，包括获取access_token的请求
+// 未设置时默认使用HTTP_PROXY、HTTPS_PROXY、NO_PROXY环境变量中的代理配置。
+// 代理在所有Option执行后才应用到http.Client上，与WithHTTPClient的先后顺序无关
+func WithProxy(proxyURL *url.URL) Option {
+	return func(d *DingTalkClient) {
+		if proxyURL != nil {
+			d.proxyURL = proxyURL
+		}
+	}
+}
+
+// applyProxy 将WithProxy设置的代理应用到http.Client上，复制http.Client及其Transport，不会修改调用方传入的对象
+func (d *DingTalkClient) applyProxy() {
+	if d.proxyURL == nil {
+		return
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if t, ok := d.httpClient.Transport.(*http.Transport); ok {
+		transport = t.Clone()
+	}
+	transport.Proxy = http.ProxyURL(d.proxyURL)
+
+	client := *d.httpClient
+	client.Transport = transport
+	d.httpClient = &client
+}
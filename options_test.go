@@ -0,0 +1,38 @@
+package sdk
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestWithProxyOrderIndependent(t *testing.T) {
+	proxyURL, _ := url.Parse("http://127.0.0.1:8080")
+	custom := &http.Client{Timeout: 3 * time.Second}
+
+	for name, opts := range map[string][]Option{
+		"proxy first":  {WithProxy(proxyURL), WithHTTPClient(custom)},
+		"client first": {WithHTTPClient(custom), WithProxy(proxyURL)},
+	} {
+		client := NewDingTalkClient("1", "key", "secret", opts...)
+
+		if client.httpClient.Timeout != custom.Timeout {
+			t.Errorf("%s: Timeout = %v, want %v", name, client.httpClient.Timeout, custom.Timeout)
+		}
+
+		transport, ok := client.httpClient.Transport.(*http.Transport)
+		if !ok || transport.Proxy == nil {
+			t.Fatalf("%s: 代理未生效", name)
+		}
+
+		req, _ := http.NewRequest(http.MethodGet, "https://oapi.dingtalk.com/gettoken", nil)
+		if got, _ := transport.Proxy(req); got == nil || got.String() != proxyURL.String() {
+			t.Errorf("%s: proxy = %v, want %v", name, got, proxyURL)
+		}
+	}
+
+	if custom.Transport != nil {
+		t.Errorf("不应修改调用方传入的http.Client")
+	}
+}
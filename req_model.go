@@ -1,5 +1,11 @@
 package sdk
 
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
 type CommonDepartmentReq struct {
 	DeptID uint64 `json:"dept_id"`
 }
@@ -9,6 +15,35 @@ type DepartmentReq struct {
 	Language Lang `json:"language,omitempty"`
 }
 
+// CreateDeptReq 创建部门，Name和ParentID为必填项
+type CreateDeptReq struct {
+	Name                string `json:"name"`
+	ParentID            uint64 `json:"parent_id"`
+	Order               int64  `json:"order,omitempty"`                  // 在父部门中的次序值
+	SourceIdentifier    string `json:"source_identifier,omitempty"`      // 部门标识字段，可用于关联外部系统
+	HideDept            bool   `json:"hide_dept,omitempty"`              // 是否隐藏本部门
+	OuterDept           bool   `json:"outer_dept,omitempty"`             // 是否限制本部门成员查看通讯录
+	CreateDeptGroup     bool   `json:"create_dept_group,omitempty"`      // 是否创建部门群
+	AutoAddUser         bool   `json:"auto_add_user,omitempty"`          // 新人是否自动加入部门群
+	GroupContainSubDept bool   `json:"group_contain_sub_dept,omitempty"` // 部门群是否包含子部门
+	Brief               string `json:"brief,omitempty"`                  // 部门简介
+}
+
+// UpdateDeptReq 更新部门，DeptID为必填项，其它字段为nil或空时不更新
+type UpdateDeptReq struct {
+	DeptID              uint64 `json:"dept_id"`
+	Name                string `json:"name,omitempty"`
+	ParentID            uint64 `json:"parent_id,omitempty"`
+	Order               *int64 `json:"order,omitempty"`
+	SourceIdentifier    string `json:"source_identifier,omitempty"`
+	HideDept            *bool  `json:"hide_dept,omitempty"`
+	OuterDept           *bool  `json:"outer_dept,omitempty"`
+	CreateDeptGroup     *bool  `json:"create_dept_group,omitempty"`
+	AutoAddUser         *bool  `json:"auto_add_user,omitempty"`
+	GroupContainSubDept *bool  `json:"group_contain_sub_dept,omitempty"`
+	Brief               string `json:"brief,omitempty"`
+}
+
 type DepartmentChildrenReq struct {
 	CommonDepartmentReq
 }
@@ -22,6 +57,72 @@ type SimpleUserReq struct {
 	Language           Lang       `json:"language"`
 }
 
+// CreateUserReq 创建员工，Name、Mobile和DeptIDList为必填项
+// DeptIDList为逗号分隔的部门id；UserID为空时由钉钉自动生成
+type CreateUserReq struct {
+	UserID     string `json:"userid,omitempty"`
+	Name       string `json:"name"`
+	Mobile     string `json:"mobile"`
+	DeptIDList string `json:"dept_id_list"`
+	Title      string `json:"title,omitempty"`      // 职位
+	Email      string `json:"email,omitempty"`      // 邮箱
+	JobNumber  string `json:"job_number,omitempty"` // 工号
+	HiredDate  int64  `json:"hired_date,omitempty"` // 入职时间，Unix时间戳，单位毫秒
+	Remark     string `json:"remark,omitempty"`     // 备注
+}
+
+func (r *CreateUserReq) validate() error {
+	if r.Name == "" || r.Mobile == "" || r.DeptIDList == "" {
+		return fmt.Errorf("创建员工时name、mobile和dept_id_list不能为空")
+	}
+	return nil
+}
+
+// UpdateUserReq 更新员工信息，UserID为必填项，其它字段为空时不更新
+type UpdateUserReq struct {
+	UserID     string `json:"userid"`
+	Name       string `json:"name,omitempty"`
+	DeptIDList string `json:"dept_id_list,omitempty"`
+	Title      string `json:"title,omitempty"`
+	Email      string `json:"email,omitempty"`
+	JobNumber  string `json:"job_number,omitempty"`
+	HiredDate  int64  `json:"hired_date,omitempty"`
+	Remark     string `json:"remark,omitempty"`
+}
+
+// PageParams 分页查询部门员工时的分页参数
+// Size为每页数量，取值1~100；游标只在各自的部门内有效，通过Cursors按部门ID指定起始游标，未指定的部门从头开始，
+// 可以使用上次查询返回的各部门游标继续同步
+type PageParams struct {
+	Size    int
+	Cursors map[uint64]int
+}
+
+func (p PageParams) validate() error {
+	if err := validatePageSize(p.Size); err != nil {
+		return err
+	}
+
+	for dept, cursor := range p.Cursors {
+		if cursor < 0 {
+			return fmt.Errorf("部门(%d)的分页游标(%d)无效", dept, cursor)
+		}
+	}
+	return nil
+}
+
+func validatePageSize(size int) error {
+	if size < 1 || size > maxPageSize {
+		return fmt.Errorf("分页大小(%d)无效, 取值范围为1~%d", size, maxPageSize)
+	}
+	return nil
+}
+
+type UserGetReq struct {
+	UserID   string `json:"userid"`
+	Language Lang   `json:"language,omitempty"`
+}
+
 type ApprovalProcessIDReq struct {
 	ProcessCode string `json:"process_code"`
 	StartTime   int64  `json:"start_time"`
@@ -31,20 +132,66 @@ type ApprovalProcessIDReq struct {
 	UserIDList  string `json:"userid_list,omitempty"`
 }
 
+// NewApprovalProcessIDReq 根据时间范围创建获取审批实例ID列表的参数
+// StartTime和EndTime为毫秒时间戳，这里统一由time.Time转换，避免误传秒级时间戳导致查询不到数据
+func NewApprovalProcessIDReq(processCode string, start, end time.Time) ApprovalProcessIDReq {
+	return ApprovalProcessIDReq{
+		ProcessCode: processCode,
+		StartTime:   start.UnixMilli(),
+		EndTime:     end.UnixMilli(),
+		Size:        20,
+		Cursor:      0,
+	}
+}
+
 type ApprovalDetailReq struct {
 	ProcessInstanceID string `json:"process_instance_id"`
 }
 
+// ApprovalFileURLReq 获取审批附件下载地址的参数
+type ApprovalFileURLReq struct {
+	Request struct {
+		ProcessInstanceID string `json:"process_instance_id"`
+		FileID            string `json:"file_id"`
+	} `json:"request"`
+}
+
 type ProcessCodeReq struct {
 	Name string `json:"name"`
 }
 
+// ProcessListReq 获取审批模板列表，Size最大为100
+type ProcessListReq struct {
+	UserID string `json:"userid,omitempty"`
+	Offset int    `json:"offset"`
+	Size   int    `json:"size"`
+}
+
 // SendMsgByRobotReq 批量发送单聊消息的参数
+// MsgParam可以是消息结构体(如*MarkdownMessage)，由SDK编码为msgParam要求的JSON字符串；
+// 也可以是已经编码好的JSON字符串，此时原样发送
 type SendMsgByRobotReq struct {
-	RobotCode string   `json:"robotCode"`
-	UserIDs   []string `json:"userIds"`
-	MsgKey    string   `json:"msgKey"`
-	MsgParam  string   `json:"msgParam"`
+	RobotCode string      `json:"robotCode"`
+	UserIDs   []string    `json:"userIds"`
+	MsgKey    string      `json:"msgKey"`
+	MsgParam  interface{} `json:"msgParam"`
+}
+
+func (r SendMsgByRobotReq) MarshalJSON() ([]byte, error) {
+	param, ok := r.MsgParam.(string)
+	if !ok {
+		raw, err := json.Marshal(r.MsgParam)
+		if err != nil {
+			return nil, fmt.Errorf("编码msgParam失败: %w", err)
+		}
+		param = string(raw)
+	}
+
+	// 使用别名类型避免递归调用MarshalJSON
+	type sendMsgByRobotReq SendMsgByRobotReq
+	req := sendMsgByRobotReq(r)
+	req.MsgParam = param
+	return json.Marshal(req)
 }
 
 type MsgContent struct {
@@ -59,3 +206,276 @@ type SnsRequest struct {
 type UserIDReq struct {
 	UnionID string `json:"unionid"`
 }
+
+type UserCountReq struct {
+	OnlyActive bool `json:"only_active"`
+}
+
+type UserIDByMobileReq struct {
+	Mobile                        string `json:"mobile"`
+	SupportExclusiveAccountSearch bool   `json:"support_exclusive_account_search,omitempty"`
+}
+
+// WorkNotifyReq 发送工作通知的参数
+// UserIDList和DeptIDList为逗号分隔的userid和部门id，ToAllUser为true时发送给企业全部员工，
+// 三种接收方式必须且只能指定一种。AgentID为空时使用创建客户端时指定的agentId
+type WorkNotifyReq struct {
+	AgentID    string         `json:"-"`
+	UserIDList string         `json:"userid_list,omitempty"`
+	DeptIDList string         `json:"dept_id_list,omitempty"`
+	ToAllUser  bool           `json:"to_all_user,omitempty"`
+	Msg        *WorkNotifyMsg `json:"msg"`
+}
+
+func (r *WorkNotifyReq) validate() error {
+	modes := 0
+	for _, set := range []bool{r.UserIDList != "", r.DeptIDList != "", r.ToAllUser} {
+		if set {
+			modes++
+		}
+	}
+
+	if modes == 0 {
+		return ErrNoRecipients
+	}
+
+	if modes > 1 {
+		return fmt.Errorf("工作通知的接收人只能指定userid_list、dept_id_list、to_all_user中的一种")
+	}
+
+	if r.Msg == nil {
+		return fmt.Errorf("工作通知消息内容不能为空")
+	}
+	return nil
+}
+
+type workNotifyReq struct {
+	AgentID int64 `json:"agent_id"`
+	WorkNotifyReq
+}
+
+// WorkNotifyMsg 工作通知的消息体，MsgType决定使用哪一个消息字段
+type WorkNotifyMsg struct {
+	MsgType  string              `json:"msgtype"`
+	Text     *WorkNotifyText     `json:"text,omitempty"`
+	Markdown *WorkNotifyMarkdown `json:"markdown,omitempty"`
+	Link     *WorkNotifyLink     `json:"link,omitempty"`
+	Image    *WorkNotifyMedia    `json:"image,omitempty"`
+	File     *WorkNotifyMedia    `json:"file,omitempty"`
+	OA       *WorkNotifyOA       `json:"oa,omitempty"`
+}
+
+type WorkNotifyText struct {
+	Content string `json:"content"`
+}
+
+type WorkNotifyMarkdown struct {
+	Title string `json:"title"`
+	Text  string `json:"text"`
+}
+
+type WorkNotifyLink struct {
+	Title      string `json:"title"`
+	Text       string `json:"text"`
+	PicURL     string `json:"picUrl"`
+	MessageURL string `json:"messageUrl"`
+}
+
+// NewTextWorkNotify 文本类型的工作通知
+func NewTextWorkNotify(content string) *WorkNotifyMsg {
+	return &WorkNotifyMsg{MsgType: "text", Text: &WorkNotifyText{Content: content}}
+}
+
+// NewMarkdownWorkNotify markdown类型的工作通知
+func NewMarkdownWorkNotify(title, text string) *WorkNotifyMsg {
+	return &WorkNotifyMsg{MsgType: "markdown", Markdown: &WorkNotifyMarkdown{Title: title, Text: text}}
+}
+
+// NewLinkWorkNotify 链接类型的工作通知
+func NewLinkWorkNotify(link WorkNotifyLink) *WorkNotifyMsg {
+	return &WorkNotifyMsg{MsgType: "link", Link: &link}
+}
+
+// WorkNotifyMedia 图片、文件类型工作通知引用的媒体文件
+type WorkNotifyMedia struct {
+	MediaID string `json:"media_id"`
+}
+
+// NewImageWorkNotify 图片类型的工作通知，mediaID通过UploadMedia获取
+func NewImageWorkNotify(mediaID string) *WorkNotifyMsg {
+	return &WorkNotifyMsg{MsgType: "image", Image: &WorkNotifyMedia{MediaID: mediaID}}
+}
+
+// NewFileWorkNotify 文件类型的工作通知，mediaID通过UploadMedia获取
+func NewFileWorkNotify(mediaID string) *WorkNotifyMsg {
+	return &WorkNotifyMsg{MsgType: "file", File: &WorkNotifyMedia{MediaID: mediaID}}
+}
+
+// WorkNotifyOA OA类型的工作通知，由带背景色的头部、正文和状态栏组成
+type WorkNotifyOA struct {
+	MessageURL   string              `json:"message_url"`              // 移动端点击消息时跳转的地址
+	PCMessageURL string              `json:"pc_message_url,omitempty"` // PC端点击消息时跳转的地址
+	Head         WorkNotifyOAHead    `json:"head"`
+	StatusBar    *WorkNotifyOAStatus `json:"status_bar,omitempty"`
+	Body         WorkNotifyOABody    `json:"body"`
+}
+
+// WorkNotifyOAHead OA消息的头部，BgColor为ARGB格式的颜色，如"FFBBBBBB"
+type WorkNotifyOAHead struct {
+	BgColor string `json:"bgcolor"`
+	Text    string `json:"text"`
+}
+
+// WorkNotifyOAStatus OA消息的状态栏
+type WorkNotifyOAStatus struct {
+	StatusValue string `json:"status_value"`
+	StatusBg    string `json:"status_bg,omitempty"` // ARGB格式的背景色
+}
+
+// WorkNotifyOABody OA消息的正文，Form最多显示6条
+type WorkNotifyOABody struct {
+	Title     string              `json:"title,omitempty"`
+	Form      []*WorkNotifyOAForm `json:"form,omitempty"`
+	Rich      *WorkNotifyOARich   `json:"rich,omitempty"`
+	Content   string              `json:"content,omitempty"`
+	Image     string              `json:"image,omitempty"` // 图片的mediaId，通过UploadMedia获取
+	FileCount string              `json:"file_count,omitempty"`
+	Author    string              `json:"author,omitempty"`
+}
+
+// WorkNotifyOAForm OA消息正文中的一行键值对
+type WorkNotifyOAForm struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// WorkNotifyOARich OA消息正文中的单行富文本，如金额和单位
+type WorkNotifyOARich struct {
+	Num  string `json:"num"`
+	Unit string `json:"unit,omitempty"`
+}
+
+// NewOAWorkNotify OA类型的工作通知
+func NewOAWorkNotify(oa WorkNotifyOA) *WorkNotifyMsg {
+	return &WorkNotifyMsg{MsgType: "oa", OA: &oa}
+}
+
+type WorkNotifyTaskReq struct {
+	AgentID int64 `json:"agent_id"`
+	TaskID  int64 `json:"task_id"`
+}
+
+type WorkNotifyRecallReq struct {
+	AgentID   int64 `json:"agent_id"`
+	MsgTaskID int64 `json:"msg_task_id"`
+}
+
+// AttendanceDateLayout 打卡结果查询中考勤日期的格式
+const AttendanceDateLayout = "2006-01-02 15:04:05"
+
+// AttendanceListReq 获取打卡结果的参数
+// WorkDateFrom和WorkDateTo的格式为AttendanceDateLayout，两者跨度不能超过7天
+type AttendanceListReq struct {
+	WorkDateFrom string   `json:"workDateFrom"`
+	WorkDateTo   string   `json:"workDateTo"`
+	UserIDList   []string `json:"userIdList"`
+	Offset       int      `json:"offset"`
+	Limit        int      `json:"limit"`
+	IsI18n       bool     `json:"isI18n,omitempty"`
+}
+
+// UserAccessTokenReq 获取用户token，GrantType为authorization_code时使用Code，为refresh_token时使用RefreshToken
+type UserAccessTokenReq struct {
+	ClientID     string `json:"clientId"`
+	ClientSecret string `json:"clientSecret"`
+	Code         string `json:"code,omitempty"`
+	RefreshToken string `json:"refreshToken,omitempty"`
+	GrantType    string `json:"grantType"`
+}
+
+// InactiveDateLayout 查询未登录钉钉的员工时日期的格式
+const InactiveDateLayout = "20060102"
+
+// InactiveUserReq 获取未登录钉钉的员工列表，Size最大为100
+type InactiveUserReq struct {
+	IsActive  bool     `json:"is_active"`
+	DeptIDs   []uint64 `json:"dept_ids,omitempty"`
+	Offset    int      `json:"offset"`
+	Size      int      `json:"size"`
+	QueryDate string   `json:"query_date"`
+}
+
+// UserSearchReq 搜索用户
+type UserSearchReq struct {
+	QueryWord string `json:"queryWord"`
+	Offset    int    `json:"offset"`
+	Size      int    `json:"size"`
+}
+
+// RoleListReq 获取角色列表，Size最大为200
+type RoleListReq struct {
+	Offset int `json:"offset"`
+	Size   int `json:"size"`
+}
+
+// RoleUserReq 获取角色下的员工列表，Size最大为100
+type RoleUserReq struct {
+	RoleID int64 `json:"role_id"`
+	Offset int   `json:"offset"`
+	Size   int   `json:"size"`
+}
+
+// ExtContactListReq 获取外部联系人列表，Size最大为100
+type ExtContactListReq struct {
+	Offset int `json:"offset"`
+	Size   int `json:"size"`
+}
+
+// CalendarEvent 日程
+type CalendarEvent struct {
+	Summary     string              `json:"summary"`
+	Description string              `json:"description,omitempty"`
+	Start       CalendarTime        `json:"start"`
+	End         CalendarTime        `json:"end"`
+	IsAllDay    bool                `json:"isAllDay"`
+	Attendees   []*CalendarAttendee `json:"attendees,omitempty"`
+	Location    *CalendarLocation   `json:"location,omitempty"`
+	Reminders   []*CalendarReminder `json:"reminders,omitempty"`
+}
+
+// CalendarTime 日程的开始或结束时间，全天日程使用Date，否则使用DateTime和TimeZone
+type CalendarTime struct {
+	Date     string `json:"date,omitempty"`     // 格式为yyyy-MM-dd
+	DateTime string `json:"dateTime,omitempty"` // ISO-8601格式
+	TimeZone string `json:"timeZone,omitempty"`
+}
+
+// NewCalendarTime 根据时间创建非全天日程的时间
+// t使用time.Local时无法得到时区名称，此时不设置TimeZone，由DateTime中的时区偏移决定
+func NewCalendarTime(t time.Time) CalendarTime {
+	ct := CalendarTime{DateTime: t.Format(time.RFC3339)}
+	if loc := t.Location(); loc != time.Local {
+		ct.TimeZone = loc.String()
+	}
+	return ct
+}
+
+// NewCalendarDate 根据时间创建全天日程的日期
+func NewCalendarDate(t time.Time) CalendarTime {
+	return CalendarTime{Date: t.Format("2006-01-02")}
+}
+
+type CalendarAttendee struct {
+	ID         string `json:"id"` // 参与者的unionId
+	IsOptional bool   `json:"isOptional,omitempty"`
+}
+
+type CalendarLocation struct {
+	DisplayName string `json:"displayName"`
+}
+
+// CalendarReminder 日程提醒，Method目前仅支持dingtalk
+type CalendarReminder struct {
+	Method  string `json:"method"`
+	Minutes int    `json:"minutes"` // 日程开始前多少分钟提醒
+}
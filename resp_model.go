@@ -1,17 +1,32 @@
 package sdk
 
+import (
+	"fmt"
+	"time"
+)
+
 type CommonResp struct {
 	ErrCode   int    `json:"errcode,omitempty"`
 	ErrMsg    string `json:"errmsg,omitempty"`
 	RequestID string `json:"request_id,omitempty"`
 }
 
+func (r *CommonResp) toError() *DingTalkError {
+	return &DingTalkError{ErrCode: r.ErrCode, ErrMsg: r.ErrMsg, RequestID: r.RequestID}
+}
+
 type AccessTokenResp struct {
 	CommonResp
 	AccessToken string `json:"access_token"`
 	ExpiresIn   int64  `json:"expires_in"`
 }
 
+type JSAPITicketResp struct {
+	CommonResp
+	Ticket    string `json:"ticket"`
+	ExpiresIn int64  `json:"expires_in"`
+}
+
 type DepartmentResp struct {
 	CommonResp
 	Result []*DepartmentNameCnf `json:"result"`
@@ -34,6 +49,54 @@ type DepartmentNameCnf struct {
 	ParentID        uint64 `json:"parent_id"`
 }
 
+type ParentDeptByUserResp struct {
+	CommonResp
+	Result *ParentDeptByUser `json:"result"`
+}
+
+type ParentDeptByUser struct {
+	ParentList []*ParentDeptIDList `json:"parent_list"`
+}
+
+type ParentDeptIDList struct {
+	ParentDeptIDList []uint64 `json:"parent_dept_id_list"`
+}
+
+type ParentDeptByDeptResp struct {
+	CommonResp
+	Result *ParentDeptByDept `json:"result"`
+}
+
+type ParentDeptByDept struct {
+	ParentIDList []uint64 `json:"parent_id_list"`
+}
+
+type DepartmentDetailResp struct {
+	CommonResp
+	Result *DepartmentDetail `json:"result"`
+}
+
+type DepartmentDetail struct {
+	DepartmentNameCnf
+	SourceIdentifier      string   `json:"source_identifier"`        // 部门标识字段
+	Order                 int64    `json:"order"`                    // 在父部门中的次序值
+	DeptManagerUserIDList []string `json:"dept_manager_userid_list"` // 部门的主管userid列表
+	OrgDeptOwner          string   `json:"org_dept_owner"`           // 企业群群主userid
+	HideDept              bool     `json:"hide_dept"`                // 是否隐藏本部门
+	OuterDept             bool     `json:"outer_dept"`               // 是否限制本部门成员查看通讯录
+	GroupContainSubDept   bool     `json:"group_contain_sub_dept"`   // 部门群是否包含子部门
+	DeptGroupChatID       string   `json:"dept_group_chat_id"`       // 部门群ID
+	Brief                 string   `json:"brief"`                    // 部门简介
+	MemberCount           int      `json:"member_count"`             // 部门员工数量
+}
+
+type DepartmentCreateResp struct {
+	CommonResp
+	Result *struct {
+		DeptID uint64 `json:"dept_id"`
+	} `json:"result"`
+}
+
 type SimpleUserResp struct {
 	CommonResp
 	Result *ListSimpleUserRes
@@ -44,6 +107,27 @@ type UserDetailResp struct {
 	Result *ListUserDetailRes
 }
 
+type UserCreateResp struct {
+	CommonResp
+	Result *struct {
+		UserID string `json:"userid"`
+	} `json:"result"`
+}
+
+type UserIDListResp struct {
+	CommonResp
+	Result *UserIDList `json:"result"`
+}
+
+type UserIDList struct {
+	UserIDList []string `json:"userid_list"`
+}
+
+type UserGetResp struct {
+	CommonResp
+	Result *DingDingUser `json:"result"`
+}
+
 type ListSimpleUserRes struct {
 	HasMore    bool          `json:"has_more"`
 	NextCursor int           `json:"next_cursor"`
@@ -63,21 +147,34 @@ type ListUserDetailRes struct {
 }
 
 type DingDingUser struct {
-	UserID       string `json:"userid"`
-	Name         string `json:"name"`
-	UnionID      string `json:"unionid"`
-	Avatar       string `json:"avatar"`
-	Mobile       string `json:"mobile"`
-	HideMobile   bool   `json:"hide_mobile"`
-	Title        string `json:"title"`
-	Email        string `json:"email"`
-	OrgEmail     string `json:"org_email"`
-	DepartIDList []int  `json:"dept_id_list"`
+	UserID       string      `json:"userid"`
+	Name         string      `json:"name"`
+	UnionID      string      `json:"unionid"`
+	Avatar       string      `json:"avatar"`
+	Mobile       string      `json:"mobile"`
+	HideMobile   bool        `json:"hide_mobile"`
+	Title        string      `json:"title"`
+	Email        string      `json:"email"`
+	OrgEmail     string      `json:"org_email"`
+	DepartIDList []uint64    `json:"dept_id_list"`
+	Active       bool        `json:"active"`     // 是否激活了钉钉
+	Admin        bool        `json:"admin"`      // 是否为企业的管理员
+	Boss         bool        `json:"boss"`       // 是否为企业的老板
+	JobNumber    string      `json:"job_number"` // 员工工号
+	HiredDate    int64       `json:"hired_date"` // 入职时间，毫秒时间戳
+	Roles        []*UserRole `json:"role_list"`  // 角色列表
+}
+
+type UserRole struct {
+	ID        int64  `json:"id"`
+	Name      string `json:"name"`
+	GroupName string `json:"group_name"`
 }
 
 type DingDingDeptNode struct {
 	Info     DingDingDeptInfo   `json:"info"`
 	Children []DingDingDeptNode `json:"children"`
+	Users    []*SimpleUser      `json:"users,omitempty"` // 部门的直属员工，仅GetDepartmentUserTree会填充
 }
 
 type DingDingDeptInfo struct {
@@ -106,16 +203,37 @@ type ProcessCodeResult struct {
 	Code string `json:"process_code"`
 }
 
+type ProcessListResp struct {
+	CommonResp
+	Result *ProcessTemplatePage `json:"result"`
+}
+
+type ProcessTemplatePage struct {
+	NextCursor  *int               `json:"next_cursor,omitempty"`
+	ProcessList []*ProcessTemplate `json:"process_list"`
+}
+
+// ProcessTemplate 审批模板
+type ProcessTemplate struct {
+	Name        string `json:"name"`
+	ProcessCode string `json:"process_code"`
+	IconURL     string `json:"icon_url"`
+	URL         string `json:"url"` // 发起审批的地址
+}
+
 type ApprovalDetail struct {
-	Title      string               `json:"title"`
-	CreateTime string               `json:"create_time"`
-	FinishTime string               `json:"finish_time"`
-	Uid        string               `json:"originator_userid"`
-	UserDeptID string               `json:"originator_dept_id"`
-	Status     string               `json:"status"`
-	BusinessID string               `json:"business_id"`
-	Result     string               `json:"result"`
-	Components []*ApprovalComponent `json:"form_component_values,omitempty"`
+	Title            string                     `json:"title"`
+	CreateTime       string                     `json:"create_time"`
+	FinishTime       string                     `json:"finish_time"`
+	Uid              string                     `json:"originator_userid"`
+	UserDeptID       string                     `json:"originator_dept_id"`
+	Status           string                     `json:"status"`
+	BusinessID       string                     `json:"business_id"`
+	Result           string                     `json:"result"`
+	Components       []*ApprovalComponent       `json:"form_component_values,omitempty"`
+	CcUserIDs        []string                   `json:"cc_userids,omitempty"`        // 抄送人userid列表
+	OperationRecords []*ApprovalOperationRecord `json:"operation_records,omitempty"` // 操作记录
+	Tasks            []*ApprovalTask            `json:"tasks,omitempty"`             // 审批任务
 }
 
 type ApprovalComponent struct {
@@ -126,6 +244,50 @@ type ApprovalComponent struct {
 	ExtValue string `json:"ext_value"`
 }
 
+// ApprovalOperationRecord 审批实例的操作记录
+type ApprovalOperationRecord struct {
+	UserID          string                `json:"userid"`
+	Date            string                `json:"date"`
+	OperationType   string                `json:"operation_type"`        // 操作类型，如EXECUTE_TASK_NORMAL、START_PROCESS_INSTANCE、ADD_REMARK等
+	OperationResult string                `json:"operation_result"`      // 操作结果: AGREE 同意，REFUSE 拒绝，NONE 未处理
+	Remark          string                `json:"remark,omitempty"`      // 评论
+	Attachments     []*ApprovalAttachment `json:"attachments,omitempty"` // 评论中的附件
+}
+
+// ApprovalAttachment 审批中的附件，字段与审批详情中操作记录的attachments一致
+// 审批详情不返回附件的下载地址，需要通过GetApprovalFileURL单独获取
+type ApprovalAttachment struct {
+	SpaceID  string `json:"space_id,omitempty"` // 附件控件中的钉盘空间ID，操作记录中的附件没有该字段
+	FileID   string `json:"file_id"`
+	FileName string `json:"file_name"`
+	FileSize int64  `json:"file_size"`
+	FileType string `json:"file_type"`
+}
+
+type ApprovalFileURLResp struct {
+	CommonResp
+	Result *ApprovalFileURL `json:"result"`
+}
+
+// ApprovalFileURL 审批附件的下载信息
+type ApprovalFileURL struct {
+	DownloadURI string `json:"download_uri"` // 附件的下载地址，有效期较短，需要及时下载
+	FileID      string `json:"file_id"`
+	SpaceID     int64  `json:"space_id"`
+}
+
+// ApprovalTask 审批实例的任务，每个审批人对应一个任务
+type ApprovalTask struct {
+	TaskID     int64  `json:"taskid"`
+	UserID     string `json:"userid"`
+	TaskStatus string `json:"task_status"` // 任务状态: NEW 未启动，RUNNING 处理中，PAUSED 暂停，CANCELED 取消，COMPLETED 完成，TERMINATED 终止
+	TaskResult string `json:"task_result"` // 结果: AGREE 同意，REFUSE 拒绝，REDIRECTED 转交
+	CreateTime string `json:"create_time"`
+	FinishTime string `json:"finish_time"`
+	ActivityID string `json:"activity_id"`
+	URL        string `json:"url"`
+}
+
 type SendMsgByRobotResp struct {
 	Code                      string   `json:"code,omitempty"`
 	ReqID                     string   `json:"requestid,omitempty"`
@@ -133,6 +295,42 @@ type SendMsgByRobotResp struct {
 	ProcessQueryKey           string   `json:"processQueryKey,omitempty"`           // 消息id
 	InvalidStaffIdList        []string `json:"invalidStaffIdList,omitempty"`        // 无效的用户userid列表。
 	FlowControlledStaffIdList []string `json:"flowControlledStaffIdList,omitempty"` // 被限流的userid列表。
+	ProcessQueryKeys          []string `json:"-"`                                   // 分批发送时每一批次的消息id
+}
+
+// merge 合并分批发送的结果，batch本身也可以是合并后的结果
+func (r *SendMsgByRobotResp) merge(batch *SendMsgByRobotResp) {
+	if r.ProcessQueryKey == "" {
+		r.Code, r.ReqID, r.Message = batch.Code, batch.ReqID, batch.Message
+		r.ProcessQueryKey = batch.ProcessQueryKey
+	}
+
+	if len(batch.ProcessQueryKeys) > 0 {
+		r.ProcessQueryKeys = append(r.ProcessQueryKeys, batch.ProcessQueryKeys...)
+	} else if batch.ProcessQueryKey != "" {
+		r.ProcessQueryKeys = append(r.ProcessQueryKeys, batch.ProcessQueryKey)
+	}
+	r.InvalidStaffIdList = append(r.InvalidStaffIdList, batch.InvalidStaffIdList...)
+	r.FlowControlledStaffIdList = append(r.FlowControlledStaffIdList, batch.FlowControlledStaffIdList...)
+}
+
+// RobotRecipient 机器人消息接收人的userid及姓名，姓名无法查询时为空
+type RobotRecipient struct {
+	UserID string
+	Name   string
+}
+
+func (r *RobotRecipient) String() string {
+	if r.Name == "" {
+		return r.UserID
+	}
+	return fmt.Sprintf("%s(%s)", r.Name, r.UserID)
+}
+
+// RobotRecipientReport 机器人消息发送结果中无效和被限流的接收人
+type RobotRecipientReport struct {
+	Invalid        []*RobotRecipient // 无效的接收人
+	FlowControlled []*RobotRecipient // 被限流的接收人
 }
 
 type DepartmentNameCnfCollection []*DepartmentNameCnf
@@ -146,6 +344,38 @@ func (c DepartmentNameCnfCollection) ForEach(fn func(item *DepartmentNameCnf) er
 	return nil
 }
 
+// Filter 返回满足pred的部门
+func (c DepartmentNameCnfCollection) Filter(pred func(item *DepartmentNameCnf) bool) DepartmentNameCnfCollection {
+	var data DepartmentNameCnfCollection
+	for _, item := range c {
+		if pred(item) {
+			data = append(data, item)
+		}
+	}
+	return data
+}
+
+// Map 对每个部门执行fn，返回fn结果组成的列表，如提取部门名称:
+//
+//	names := depts.Map(func(item *DepartmentNameCnf) interface{} { return item.Name })
+func (c DepartmentNameCnfCollection) Map(fn func(item *DepartmentNameCnf) interface{}) []interface{} {
+	data := make([]interface{}, 0, len(c))
+	for _, item := range c {
+		data = append(data, fn(item))
+	}
+	return data
+}
+
+// FindByID 根据部门ID查找部门，找不到时返回nil
+func (c DepartmentNameCnfCollection) FindByID(id uint64) *DepartmentNameCnf {
+	for _, item := range c {
+		if item.DeptID == id {
+			return item
+		}
+	}
+	return nil
+}
+
 type SnsResponse struct {
 	CommonResp
 	UserInfo *SnsUserInfo `json:"user_info"`
@@ -158,6 +388,13 @@ type SnsUserInfo struct {
 	MainOrgAuthHighLevel bool   `json:"main_org_auth_high_level"`
 }
 
+// ScanQrCodeUser 扫码登录的用户信息
+type ScanQrCodeUser struct {
+	SnsUserInfo
+	UserID      string `json:"userid"`
+	ContactType int    `json:"contact_type"` // 联系类型: 0 企业内部员工，1 企业外部联系人
+}
+
 type UserIDResponse struct {
 	CommonResp
 	Result *UserGetByUnionIdResponse `json:"result"`
@@ -167,3 +404,244 @@ type UserGetByUnionIdResponse struct {
 	UserID      string `json:"userid"`
 	ContactType int    `json:"contact_type"` // 联系类型: 0 企业内部员工，1 企业外部联系人
 }
+
+type UserIDByMobileResponse struct {
+	CommonResp
+	Result *UserGetByMobileResponse `json:"result"`
+}
+
+type UserGetByMobileResponse struct {
+	UserID                     string   `json:"userid"`
+	ExclusiveAccountUserIDList []string `json:"exclusive_account_userid_list,omitempty"` // 专属帐号的userid列表
+}
+
+type WorkNotifyResp struct {
+	CommonResp
+	TaskID int64 `json:"task_id"`
+}
+
+type WorkNotifyProgressResp struct {
+	CommonResp
+	Progress *WorkNotifyProgress `json:"progress"`
+}
+
+type WorkNotifyProgress struct {
+	ProgressInPercent int `json:"progress_in_percent"` // 取值0~100，表示处理的百分比
+	Status            int `json:"status"`              // 任务执行状态: 0 未开始，1 处理中，2 处理完毕
+}
+
+type WorkNotifySendResultResp struct {
+	CommonResp
+	SendResult *WorkNotifySendResult `json:"send_result"`
+}
+
+type WorkNotifySendResult struct {
+	InvalidUserIDList   []string               `json:"invalid_user_id_list"`   // 无效的userid
+	ForbiddenUserIDList []string               `json:"forbidden_user_id_list"` // 因发送消息过于频繁或超量而被流控过滤后实际未发送的userid
+	FailedUserIDList    []string               `json:"failed_user_id_list"`    // 发送失败的userid
+	ReadUserIDList      []string               `json:"read_user_id_list"`      // 已读消息的userid
+	UnreadUserIDList    []string               `json:"unread_user_id_list"`    // 未读消息的userid
+	InvalidDeptIDList   []uint64               `json:"invalid_dept_id_list"`   // 无效的部门ID
+	ForbiddenList       []*WorkNotifyForbidden `json:"forbidden_list"`         // 推送被禁止的具体原因
+}
+
+type WorkNotifyForbidden struct {
+	Code   string `json:"code"`
+	Count  int    `json:"count"`
+	UserID string `json:"userid"`
+}
+
+type AttendanceListResp struct {
+	CommonResp
+	HasMore      bool                `json:"hasMore"`
+	RecordResult []*AttendanceRecord `json:"recordresult"`
+}
+
+type AttendanceRecord struct {
+	ID             int64  `json:"id"`
+	RecordID       int64  `json:"recordId"`
+	GroupID        int64  `json:"groupId"`
+	PlanID         int64  `json:"planId"`
+	UserID         string `json:"userId"`
+	WorkDate       int64  `json:"workDate"`       // 工作日，毫秒时间戳
+	CheckType      string `json:"checkType"`      // 考勤类型: OnDuty 上班，OffDuty 下班
+	TimeResult     string `json:"timeResult"`     // 时间结果: Normal 正常，Early 早退，Late 迟到，SeriousLate 严重迟到，Absenteeism 旷工迟到，NotSigned 未打卡
+	LocationResult string `json:"locationResult"` // 位置结果: Normal 范围内，Outside 范围外，NotSigned 未打卡
+	BaseCheckTime  int64  `json:"baseCheckTime"`  // 计算迟到和早退的基准时间，毫秒时间戳
+	UserCheckTime  int64  `json:"userCheckTime"`  // 实际打卡时间，毫秒时间戳
+	SourceType     string `json:"sourceType"`     // 数据来源
+	ProcInstID     string `json:"procInstId,omitempty"`
+}
+
+type UserCountResp struct {
+	CommonResp
+	Result *UserCount `json:"result"`
+}
+
+type UserCount struct {
+	Count int `json:"count"`
+}
+
+type AdminListResp struct {
+	CommonResp
+	Result []*AdminInfo `json:"result"`
+}
+
+type AdminInfo struct {
+	UserID   string `json:"userid"`
+	SysLevel int    `json:"sys_level"` // 管理员角色: 1 主管理员，2 子管理员
+}
+
+type AdminScopeResp struct {
+	CommonResp
+	DeptIDs []uint64 `json:"dept_ids"`
+}
+
+type UserAccessTokenResp struct {
+	AccessToken  string `json:"accessToken"`
+	RefreshToken string `json:"refreshToken"`
+	ExpireIn     int64  `json:"expireIn"`
+	CorpID       string `json:"corpId"`
+}
+
+// UserAccessToken 用户的access_token，ExpireTime为根据有效期计算得到的过期时间
+type UserAccessToken struct {
+	AccessToken  string
+	RefreshToken string
+	ExpireTime   time.Time
+	CorpID       string // 用户所选企业的corpId
+}
+
+type InactiveUserResp struct {
+	CommonResp
+	Result *InactiveUserPage `json:"result"`
+}
+
+type InactiveUserPage struct {
+	HasMore    bool     `json:"has_more"`
+	NextCursor int      `json:"next_cursor"`
+	List       []string `json:"list"`
+}
+
+// ActiveUserStatistics 活跃员工统计，Date格式为InactiveDateLayout
+type ActiveUserStatistics struct {
+	Date         string `json:"date"`
+	DailyActive  int    `json:"daily_active"`  // 当天登录过钉钉的员工数
+	WeeklyActive int    `json:"weekly_active"` // 最近7天(含当天)登录过钉钉的去重员工数
+}
+
+type UserSearchResp struct {
+	HasMore    bool     `json:"hasMore"`
+	TotalCount int      `json:"totalCount"`
+	List       []string `json:"list"`
+}
+
+type RoleListResp struct {
+	CommonResp
+	Result *RoleGroupPage `json:"result"`
+}
+
+type RoleGroupPage struct {
+	HasMore bool         `json:"hasMore"`
+	List    []*RoleGroup `json:"list"`
+}
+
+// RoleGroup 角色组
+type RoleGroup struct {
+	GroupID int64   `json:"groupId"`
+	Name    string  `json:"name"`
+	Roles   []*Role `json:"roles"`
+}
+
+// Role 角色
+type Role struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+}
+
+type RoleUsersResp struct {
+	CommonResp
+	Result *RoleUserPage `json:"result"`
+}
+
+type RoleUserPage struct {
+	HasMore    bool               `json:"hasMore"`
+	NextCursor int                `json:"nextCursor"`
+	List       RoleUserCollection `json:"list"`
+}
+
+// RoleUser 角色下的员工
+type RoleUser struct {
+	UserID       string             `json:"userid"`
+	Name         string             `json:"name"`
+	ManageScopes []*RoleManageScope `json:"manageScopes"` // 员工在该角色下的管理范围
+}
+
+type RoleUserCollection []*RoleUser
+
+// GroupByDept 按管理范围的部门汇总角色下员工的userid
+// 一个员工可能有多个管理范围，会出现在多个部门下；未设置管理范围的员工归入部门0
+func (c RoleUserCollection) GroupByDept() map[uint64][]string {
+	groups := make(map[uint64][]string)
+	for _, user := range c {
+		if len(user.ManageScopes) == 0 {
+			groups[0] = append(groups[0], user.UserID)
+			continue
+		}
+
+		for _, scope := range user.ManageScopes {
+			groups[scope.DeptID] = append(groups[scope.DeptID], user.UserID)
+		}
+	}
+	return groups
+}
+
+// RoleManageScope 角色的管理范围(部门)
+type RoleManageScope struct {
+	DeptID uint64 `json:"dept_id"`
+	Name   string `json:"name"`
+}
+
+type ExtContactListResp struct {
+	CommonResp
+	Results []*ExternalContact `json:"results"`
+}
+
+// ExternalContact 外部联系人
+type ExternalContact struct {
+	UserID         string   `json:"userid"`
+	Name           string   `json:"name"`
+	Mobile         string   `json:"mobile"`
+	StateCode      string   `json:"state_code"`       // 手机号国家码
+	CompanyName    string   `json:"company_name"`     // 企业名称
+	Title          string   `json:"title"`            // 职位
+	Email          string   `json:"email"`            // 邮箱
+	Address        string   `json:"address"`          // 地址
+	Remark         string   `json:"remark"`           // 备注
+	FollowerUserID string   `json:"follower_user_id"` // 负责人的userid
+	LabelIDs       []int64  `json:"label_ids"`        // 标签
+	ShareDeptIDs   []uint64 `json:"share_dept_ids"`   // 共享给的部门
+	ShareUserIDs   []string `json:"share_user_ids"`   // 共享给的员工userid
+}
+
+type CalendarEventResp struct {
+	ID          string              `json:"id"`
+	Summary     string              `json:"summary"`
+	Description string              `json:"description"`
+	Start       CalendarTime        `json:"start"`
+	End         CalendarTime        `json:"end"`
+	IsAllDay    bool                `json:"isAllDay"`
+	Attendees   []*CalendarAttendee `json:"attendees"`
+	Organizer   *CalendarAttendee   `json:"organizer"`
+	Location    *CalendarLocation   `json:"location"`
+	Reminders   []*CalendarReminder `json:"reminders"`
+	CreateTime  string              `json:"createTime"`
+	UpdateTime  string              `json:"updateTime"`
+}
+
+type MediaUploadResp struct {
+	CommonResp
+	Type      string `json:"type"`
+	MediaID   string `json:"media_id"`
+	CreatedAt int64  `json:"created_at"`
+}
@@ -0,0 +1,150 @@
+package sdk
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go.uber.org/multierr"
+)
+
+// maxRateLimitRetries 触发钉钉接口频率限制时最多重试的次数
+const maxRateLimitRetries = 3
+
+// isRateLimited 判断错误码是否表示触发了钉钉接口的频率限制
+func isRateLimited(errcode int) bool {
+	switch errcode {
+	case ErrCodeRateLimited, ErrCodeAppRateLimited, ErrCodeCorpRateLimited, ErrCodeQPSLimited:
+		return true
+	}
+	return false
+}
+
+// isRateLimitedError 判断请求的错误是否为触发了频率限制，是客户端默认的重试条件
+func isRateLimitedError(err error) bool {
+	var dtErr *DingTalkError
+	if errors.As(err, &dtErr) {
+		return isRateLimited(dtErr.ErrCode)
+	}
+
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.StatusCode == http.StatusTooManyRequests
+	}
+	return false
+}
+
+// newBackoff 按照客户端配置的退避参数创建Backoff
+func (d *DingTalkClient) newBackoff() *Backoff {
+	if d.retryBaseDelay <= 0 && d.retryMaxDelay <= 0 {
+		return NewBackoff()
+	}
+
+	baseDelay, maxDelay := d.retryBaseDelay, d.retryMaxDelay
+	if baseDelay <= 0 {
+		baseDelay = defaultBaseDelay
+	}
+	if maxDelay <= 0 {
+		maxDelay = defaultMaxDelay
+	}
+
+	backOff, err := NewBackoffWithParams(baseDelay, maxDelay, defaultFactor, defaultJitter)
+	if err != nil {
+		d.log.Warnf("重试退避参数无效, 使用默认参数: %v", err)
+		return NewBackoff()
+	}
+	return backOff
+}
+
+// retryAfter 解析响应头中的Retry-After，支持秒数和HTTP时间两种格式，没有指定时返回0
+func retryAfter(header http.Header) time.Duration {
+	val := header.Get("Retry-After")
+	if val == "" {
+		return 0
+	}
+
+	if secs, err := strconv.Atoi(val); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+
+	if t, err := http.ParseTime(val); err == nil {
+		if wait := time.Until(t); wait > 0 {
+			return wait
+		}
+	}
+	return 0
+}
+
+// waitRateLimit 触发频率限制等可重试的错误后等待一段时间再重试
+// 钉钉通过Retry-After给出等待时间时以其为准，否则按照backOff计算等待时间
+func (d *DingTalkClient) waitRateLimit(ctx context.Context, backOff *Backoff, retries int, wait time.Duration) error {
+	if wait <= 0 {
+		wait = backOff.Duration(retries + 1)
+	}
+
+	d.log.Warnf("请求钉钉接口失败, %v后进行第%d次重试", wait, retries+1)
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		if d.ctx.Err() != nil {
+			return ErrClientClosed
+		}
+		return ctx.Err()
+	case <-d.ctx.Done():
+		return ErrClientClosed
+	case <-timer.C:
+		return nil
+	}
+}
+
+// Retry 执行fn，fn返回可重试的错误时按照Backoff等待后重试，最多执行maxAttempts次
+// 可重试的错误包括DingTalkError.IsRetryable()为true的错误以及HTTP 429(通过errors.As从SDK方法返回的错误中识别)，其它错误直接返回。
+// 注意SDK方法内部已经会按WithMaxRetries重试触发频率限制的请求，fn中的每次调用都可能包含多次请求。
+// 等待重试期间ctx被取消时返回最后一次的错误与ctx.Err()的组合，可以通过errors.Is(err, context.Canceled)判断。
+// 用于为SDK内部没有重试的接口增加重试:
+//
+//	err := sdk.Retry(ctx, 3, func() error {
+//		_, err := client.GetUserDetail(userid, sdk.ChineseLanguage)
+//		return err
+//	})
+func Retry(ctx context.Context, maxAttempts int, fn func() error) error {
+	return retry(ctx, maxAttempts, NewBackoff(), fn)
+}
+
+func retry(ctx context.Context, maxAttempts int, backOff *Backoff, fn func() error) error {
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		if err = fn(); err == nil || !isRetryableError(err) || attempt >= maxAttempts {
+			return err
+		}
+
+		timer := time.NewTimer(backOff.Duration(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return multierr.Append(err, ctx.Err())
+		case <-timer.C:
+		}
+	}
+}
+
+func isRetryableError(err error) bool {
+	var dtErr *DingTalkError
+	if errors.As(err, &dtErr) {
+		return dtErr.IsRetryable()
+	}
+
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.StatusCode == http.StatusTooManyRequests
+	}
+	return false
+}
@@ -0,0 +1,118 @@
+package sdk
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newTestBackoff(t *testing.T) *Backoff {
+	t.Helper()
+
+	backOff, err := NewBackoffWithParams(testRetryDelay, testRetryDelay, defaultFactor, 0)
+	if err != nil {
+		t.Fatalf("NewBackoffWithParams: %v", err)
+	}
+	return backOff
+}
+
+// rateLimitedMux 返回对/topapi/v2/user/get始终响应429的mux，并统计请求次数
+func rateLimitedMux(calls *int32) *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/topapi/v2/user/get", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(calls, 1)
+		w.WriteHeader(http.StatusTooManyRequests)
+	})
+	return mux
+}
+
+func TestClientRetriesRateLimited(t *testing.T) {
+	var calls int32
+	client := newTestClient(t, rateLimitedMux(&calls))
+
+	_, err := client.GetUserDetail("u1", ChineseLanguage)
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) || httpErr.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("err = %v, want HTTPError(429)", err)
+	}
+
+	if want := int32(maxRateLimitRetries + 1); calls != want {
+		t.Errorf("calls = %d, want %d", calls, want)
+	}
+}
+
+func TestRetryPublicMethod(t *testing.T) {
+	var calls int32
+	client := newTestClient(t, rateLimitedMux(&calls), WithMaxRetries(0))
+
+	err := retry(context.Background(), 3, newTestBackoff(t), func() error {
+		_, err := client.GetUserDetail("u1", ChineseLanguage)
+		return err
+	})
+	if !isRetryableError(err) {
+		t.Fatalf("err = %v, want 429", err)
+	}
+
+	if calls != 3 {
+		t.Errorf("calls = %d, want 3", calls)
+	}
+}
+
+func TestRetryStopsOnNonRetryable(t *testing.T) {
+	var calls int
+	want := &DingTalkError{ErrCode: ErrCodeInvalidToken}
+
+	err := retry(context.Background(), 3, newTestBackoff(t), func() error {
+		calls++
+		return want
+	})
+	if !errors.Is(err, want) || calls != 1 {
+		t.Fatalf("err = %v, calls = %d, want %v after 1 call", err, calls, want)
+	}
+}
+
+func TestRetryContextCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var calls int
+	err := Retry(ctx, 3, func() error {
+		calls++
+		return &DingTalkError{ErrCode: ErrCodeRateLimited}
+	})
+
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("errors.Is(err, context.Canceled) = false, err = %v", err)
+	}
+
+	var dtErr *DingTalkError
+	if !errors.As(err, &dtErr) || dtErr.ErrCode != ErrCodeRateLimited {
+		t.Errorf("err = %v, want DingTalkError(%d)", err, ErrCodeRateLimited)
+	}
+
+	if calls != 1 {
+		t.Errorf("calls = %d, want 1", calls)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	cases := map[string]time.Duration{
+		"":    0,
+		"3":   3 * time.Second,
+		"-1":  0,
+		"bad": 0,
+	}
+
+	for val, want := range cases {
+		header := http.Header{}
+		if val != "" {
+			header.Set("Retry-After", val)
+		}
+		if got := retryAfter(header); got != want {
+			t.Errorf("retryAfter(%q) = %v, want %v", val, got, want)
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package sdk
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strconv"
+)
+
+// SignTimestamp 使用appSecret对毫秒时间戳签名，签名结果为HmacSHA256 -> Base64编码 -> urlencode编码
+// 用于sns接口、回调等需要对时间戳签名的场景
+func SignTimestamp(appSecret string, ts int64) string {
+	return url.QueryEscape(hmacSHA256Base64(appSecret, strconv.FormatInt(ts, 10)))
+}
+
+// ConfigSignature 计算前端调用dd.config所需的签名
+// url为调用dd.config的页面地址(不含#及其后面部分)，已经urlencode的地址会先解码再参与签名
+func ConfigSignature(ticket, nonce string, timestamp int64, pageURL string) string {
+	if decoded, err := url.QueryUnescape(pageURL); err == nil {
+		pageURL = decoded
+	}
+
+	plain := fmt.Sprintf("jsapi_ticket=%s&noncestr=%s&timestamp=%d&url=%s", ticket, nonce, timestamp, pageURL)
+	digest := sha1.Sum([]byte(plain))
+	return hex.EncodeToString(digest[:])
+}
+
+func hmacSHA256Base64(secret, content string) string {
+	hashFn := hmac.New(sha256.New, []byte(secret))
+	hashFn.Write([]byte(content))
+	return base64.StdEncoding.EncodeToString(hashFn.Sum(nil))
+}
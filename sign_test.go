@@ -0,0 +1,46 @@
+package sdk
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"net/url"
+	"testing"
+)
+
+func TestSignTimestamp(t *testing.T) {
+	const (
+		secret = "app-secret"
+		ts     = int64(1700000000000)
+	)
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("1700000000000"))
+	sign := base64.StdEncoding.EncodeToString(mac.Sum(nil))
+
+	got := SignTimestamp(secret, ts)
+	if got != url.QueryEscape(sign) {
+		t.Fatalf("SignTimestamp = %s, want %s", got, url.QueryEscape(sign))
+	}
+
+	if decoded, err := url.QueryUnescape(got); err != nil || decoded != sign {
+		t.Errorf("签名应为urlencode编码: %s", got)
+	}
+}
+
+func TestConfigSignature(t *testing.T) {
+	const plain = "jsapi_ticket=ticket&noncestr=nonce&timestamp=1700000000&url=https://example.com/page?a=1&b=2"
+	digest := sha1.Sum([]byte(plain))
+	want := hex.EncodeToString(digest[:])
+
+	for _, pageURL := range []string{
+		"https://example.com/page?a=1&b=2",
+		url.QueryEscape("https://example.com/page?a=1&b=2"),
+	} {
+		if got := ConfigSignature("ticket", "nonce", 1700000000, pageURL); got != want {
+			t.Errorf("ConfigSignature(%s) = %s, want %s", pageURL, got, want)
+		}
+	}
+}
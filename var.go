@@ -1,5 +1,37 @@
 package sdk
 
+import "time"
+
+// defaultConcurrency 并发请求钉钉接口时默认的并发数，避免触发钉钉的接口频率限制
+const defaultConcurrency = 5
+
+// robotBatchSize 机器人批量发送单聊消息时，每次请求最多的接收人数量
+const robotBatchSize = 20
+
+// defaultTokenSafetyMargin access_token默认提前过期的时间，提前刷新避免使用即将过期的access_token
+const defaultTokenSafetyMargin = 5 * time.Minute
+
+// defaultUserAgent 请求钉钉接口时默认的User-Agent
+const defaultUserAgent = "go-ding-sdk/1.0"
+
+// robotMaxAttempts 机器人批量发送一个批次时最多的请求次数(包括第一次)，WithMaxRetries设置得更大时也不会超过该次数
+const robotMaxAttempts = 3
+
+// robotDedupTTL 幂等发送机器人消息时，已发送批次的记录保留的时间
+const robotDedupTTL = 24 * time.Hour
+
+// defaultWebhookTimeout 自定义群机器人发送消息的默认超时时间
+const defaultWebhookTimeout = 10 * time.Second
+
+// maxPageSize 分页查询部门员工时每页的最大数量
+const maxPageSize = 100
+
+// maxSnippetSize 错误信息中附带的响应内容的最大字节数
+const maxSnippetSize = 512
+
+// RootDeptID 企业根部门的ID
+const RootDeptID uint64 = 1
+
 type Lang string
 type OrderField string
 
@@ -12,3 +44,11 @@ var (
 	ModifyDesc      OrderField = "modify_desc" // 代表按照部门信息修改时间降序。
 	Custom          OrderField = "custom"      // 代表用户定义(未定义时按照拼音)排序。
 )
+
+// 上传媒体文件的类型
+const (
+	MediaImage = "image" // 图片，最大20MB，支持jpg、gif、png、bmp格式
+	MediaVoice = "voice" // 语音，最大2MB，支持amr、mp3、wav格式
+	MediaVideo = "video" // 视频，最大20MB，支持mp4格式
+	MediaFile  = "file"  // 普通文件，最大20MB，支持doc、docx、xls、xlsx、ppt、pptx、zip、pdf、rar格式
+)
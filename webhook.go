@@ -0,0 +1,194 @@
+package sdk
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// WebhookMessage 自定义群机器人消息，MsgType对应请求中的msgtype
+type WebhookMessage interface {
+	MsgType() string
+}
+
+// WebhookAt 群消息中需要@的人，仅文本和markdown消息支持
+// markdown消息需要在Text中包含"@手机号"或"@userid"才会在消息中显示
+type WebhookAt struct {
+	AtMobiles []string `json:"atMobiles,omitempty"` // 被@人的手机号
+	AtUserIds []string `json:"atUserIds,omitempty"` // 被@人的userid
+	IsAtAll   bool     `json:"isAtAll,omitempty"`   // 是否@所有人
+}
+
+func (a *WebhookAt) at() *WebhookAt {
+	return a
+}
+
+func (a *WebhookAt) isEmpty() bool {
+	return len(a.AtMobiles) == 0 && len(a.AtUserIds) == 0 && !a.IsAtAll
+}
+
+// WebhookText 文本消息
+type WebhookText struct {
+	Content   string `json:"content"`
+	WebhookAt `json:"-"`
+}
+
+func (m *WebhookText) MsgType() string {
+	return "text"
+}
+
+// WebhookMarkdown markdown消息
+type WebhookMarkdown struct {
+	Title     string `json:"title"`
+	Text      string `json:"text"`
+	WebhookAt `json:"-"`
+}
+
+func (m *WebhookMarkdown) MsgType() string {
+	return "markdown"
+}
+
+// WebhookLink 链接消息
+type WebhookLink struct {
+	Title      string `json:"title"`
+	Text       string `json:"text"`
+	PicURL     string `json:"picUrl,omitempty"`
+	MessageURL string `json:"messageUrl"`
+}
+
+func (m *WebhookLink) MsgType() string {
+	return "link"
+}
+
+// WebhookActionCard 卡片消息
+// 设置SingleTitle和SingleURL时为整体跳转的卡片，否则使用Buttons作为独立跳转的按钮
+type WebhookActionCard struct {
+	Title          string              `json:"title"`
+	Text           string              `json:"text"`
+	SingleTitle    string              `json:"singleTitle,omitempty"`
+	SingleURL      string              `json:"singleURL,omitempty"`
+	BtnOrientation string              `json:"btnOrientation,omitempty"` // 0: 按钮竖直排列，1: 按钮横向排列
+	Buttons        []*WebhookActionBtn `json:"btns,omitempty"`
+}
+
+// WebhookActionBtn 卡片消息的按钮
+type WebhookActionBtn struct {
+	Title     string `json:"title"`
+	ActionURL string `json:"actionURL"`
+}
+
+func (m *WebhookActionCard) MsgType() string {
+	return "actionCard"
+}
+
+// RobotWebhook 自定义群机器人，通过群机器人的Webhook地址发送群消息
+// 机器人安全设置为加签时需要指定secret，请求会自动附带timestamp和sign参数
+type RobotWebhook struct {
+	webhook    string
+	secret     string
+	httpClient *http.Client
+}
+
+// NewRobotWebhook 使用Webhook地址和加签密钥(SEC开头，未启用加签时为空)创建自定义群机器人
+// 请求使用超时时间为defaultWebhookTimeout的http.Client，需要代理或自定义超时时使用NewRobotWebhookWithClient
+func NewRobotWebhook(webhook, secret string) *RobotWebhook {
+	return NewRobotWebhookWithClient(webhook, secret, nil)
+}
+
+// NewRobotWebhookWithClient 与NewRobotWebhook相同，使用指定的http.Client发送请求，可用于设置代理、超时或在测试中替换
+// client为nil时使用超时时间为defaultWebhookTimeout的http.Client
+func NewRobotWebhookWithClient(webhook, secret string, client *http.Client) *RobotWebhook {
+	if client == nil {
+		client = &http.Client{Timeout: defaultWebhookTimeout}
+	}
+
+	return &RobotWebhook{
+		webhook:    webhook,
+		secret:     secret,
+		httpClient: client,
+	}
+}
+
+// Send 发送群消息
+func (r *RobotWebhook) Send(msg WebhookMessage) error {
+	return r.SendContext(context.Background(), msg)
+}
+
+// SendContext 发送群消息，可通过ctx取消请求
+func (r *RobotWebhook) SendContext(ctx context.Context, msg WebhookMessage) error {
+	reqUrl, err := r.signedURL(time.Now().UnixNano() / 1000000)
+	if err != nil {
+		return err
+	}
+
+	body := map[string]interface{}{
+		"msgtype":     msg.MsgType(),
+		msg.MsgType(): msg,
+	}
+	if m, ok := msg.(interface{ at() *WebhookAt }); ok && !m.at().isEmpty() {
+		body["at"] = m.at()
+	}
+
+	param, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("编码群机器人消息失败: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, reqUrl, bytes.NewReader(param))
+	if err != nil {
+		return fmt.Errorf("创建HTTP请求失败: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	req.Header.Set("User-Agent", defaultUserAgent)
+
+	resp, err := r.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("发送群机器人消息失败: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	payload, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("读取失败: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return &HTTPError{StatusCode: resp.StatusCode, Status: resp.Status, Body: bodySnippet(payload)}
+	}
+
+	data := new(CommonResp)
+	if err = decodeResult(payload, data); err != nil {
+		return fmt.Errorf("发送群机器人消息失败: %w", err)
+	}
+
+	if data.ErrCode != 0 {
+		return fmt.Errorf("发送群机器人消息失败: %w", data.toError())
+	}
+	return nil
+}
+
+// signedURL 启用加签时，在Webhook地址上附加timestamp和sign参数
+// 签名为 timestamp+"\n"+secret 以secret为密钥计算HmacSHA256后Base64编码
+func (r *RobotWebhook) signedURL(ts int64) (string, error) {
+	if r.secret == "" {
+		return r.webhook, nil
+	}
+
+	u, err := url.Parse(r.webhook)
+	if err != nil {
+		return "", fmt.Errorf("无效的Webhook地址: %w", err)
+	}
+
+	timestamp := strconv.FormatInt(ts, 10)
+	query := u.Query()
+	query.Set("timestamp", timestamp)
+	query.Set("sign", hmacSHA256Base64(r.secret, timestamp+"\n"+r.secret))
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
@@ -0,0 +1,83 @@
+package sdk
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestRobotWebhookSendSigned(t *testing.T) {
+	const secret = "SECtest"
+
+	var body map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		ts := query.Get("timestamp")
+		if _, err := strconv.ParseInt(ts, 10, 64); err != nil {
+			t.Errorf("timestamp无效: %q", ts)
+		}
+
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write([]byte(ts + "\n" + secret))
+		if want := base64.StdEncoding.EncodeToString(mac.Sum(nil)); query.Get("sign") != want {
+			t.Errorf("sign = %q, want %q", query.Get("sign"), want)
+		}
+
+		payload, _ := io.ReadAll(r.Body)
+		if err := json.Unmarshal(payload, &body); err != nil {
+			t.Errorf("请求体无效: %v", err)
+		}
+		_, _ = w.Write([]byte(`{"errcode":0,"errmsg":"ok"}`))
+	}))
+	defer server.Close()
+
+	robot := NewRobotWebhookWithClient(server.URL+"/robot/send?access_token=x", secret, server.Client())
+	msg := &WebhookText{Content: "hello", WebhookAt: WebhookAt{AtUserIds: []string{"u1"}}}
+	if err := robot.Send(msg); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	if body["msgtype"] != "text" {
+		t.Errorf("msgtype = %v", body["msgtype"])
+	}
+	if _, ok := body["at"]; !ok {
+		t.Errorf("缺少at: %v", body)
+	}
+}
+
+func TestRobotWebhookSendError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("sign") != "" {
+			t.Errorf("未设置secret时不应加签")
+		}
+		_, _ = w.Write([]byte(`{"errcode":310000,"errmsg":"keywords not in content"}`))
+	}))
+	defer server.Close()
+
+	robot := NewRobotWebhookWithClient(server.URL, "", server.Client())
+	err := robot.Send(&WebhookMarkdown{Title: "t", Text: "x"})
+
+	var dtErr *DingTalkError
+	if !errors.As(err, &dtErr) || dtErr.ErrCode != 310000 {
+		t.Fatalf("err = %v, want DingTalkError(310000)", err)
+	}
+}
+
+func TestNewRobotWebhookDefaultTimeout(t *testing.T) {
+	robot := NewRobotWebhook("https://oapi.dingtalk.com/robot/send", "")
+	if robot.httpClient == http.DefaultClient || robot.httpClient.Timeout != defaultWebhookTimeout {
+		t.Fatalf("默认http.Client的超时时间 = %v, want %v", robot.httpClient.Timeout, defaultWebhookTimeout)
+	}
+
+	if got, _ := robot.signedURL(time.Now().UnixNano() / 1000000); got != robot.webhook {
+		t.Errorf("未设置secret时signedURL = %q", got)
+	}
+}